@@ -79,9 +79,11 @@ func (p *Program) TokenLiteral() string {
 
 // LetStatement prepares a Let statement node
 type LetStatement struct {
-	Token token.Token // the token.LET token
-	Name  *Identifier // call Identifier() for IDENT
-	Value Expression  // literal type
+	Token          token.Token // the token.LET token
+	Name           *Identifier // call Identifier() for IDENT
+	Type           *Identifier // optional type annotation, the int in let x: int = 5; nil when there isn't one
+	Value          Expression  // literal type
+	LeadingComment string      // the // comments directly before the statement, one per line, when the lexer keeps comments
 }
 
 // statementNode contains LetStatement
@@ -99,6 +101,11 @@ func (ls *LetStatement) String() string {
 
 	out.WriteString(ls.TokenLiteral() + " ")
 	out.WriteString(ls.Name.String())
+
+	if ls.Type != nil {
+		out.WriteString(": " + ls.Type.String())
+	}
+
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -110,10 +117,45 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// ConstStatement prepares a const statement node, a binding that can't be reassigned
+type ConstStatement struct {
+	Token          token.Token // the token.CONST token
+	Name           *Identifier
+	Value          Expression
+	LeadingComment string // the // comments directly before the statement, when the lexer keeps comments
+}
+
+// statementNode contains ConstStatement
+func (cs *ConstStatement) statementNode() {
+}
+
+// TokenLiteral returns the literal type of ConstStatement's token
+func (cs *ConstStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+
+// String writing function for const statement
+func (cs *ConstStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(cs.TokenLiteral() + " ")
+	out.WriteString(cs.Name.String())
+	out.WriteString(" = ")
+
+	if cs.Value != nil {
+		out.WriteString(cs.Value.String())
+	}
+
+	out.WriteString(";")
+
+	return out.String()
+}
+
 // ReturnStatement prepares a Return statement node
 type ReturnStatement struct {
-	Token       token.Token // the return token
-	ReturnValue Expression
+	Token          token.Token // the return token
+	ReturnValue    Expression
+	LeadingComment string // the // comments directly before the statement, when the lexer keeps comments
 }
 
 // statementNode contains ReturnStatement
@@ -141,8 +183,9 @@ func (rs *ReturnStatement) String() string {
 
 // ExpressionStatement prepares an Expression statement node type
 type ExpressionStatement struct {
-	Token      token.Token // This field contains the first token of the expression
-	Expression Expression  // This field contains the expression
+	Token          token.Token // This field contains the first token of the expression
+	Expression     Expression  // This field contains the expression
+	LeadingComment string      // the // comments directly before the statement, when the lexer keeps comments
 }
 
 // statementNode contains ExpressionStatement
@@ -180,6 +223,25 @@ func (il *IntegerLiteral) String() string {
 	return il.Token.Literal
 }
 
+// FloatLiteral structure for a floating-point literal expression
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+// FloatLiteral is assigned to an AST expression node
+func (fl *FloatLiteral) expressionNode() {}
+
+// TokenLiteral contains the literal type of float literal
+func (fl *FloatLiteral) TokenLiteral() string {
+	return fl.Token.Literal
+}
+
+// String writing function for FloatLiteral
+func (fl *FloatLiteral) String() string {
+	return fl.Token.Literal
+}
+
 // StringLiteral structure for a String literal expression
 type StringLiteral struct {
 	Token token.Token
@@ -273,6 +335,158 @@ func (b *Boolean) String() string {
 	return b.Token.Literal
 }
 
+// NullLiteral structure for the null keyword
+type NullLiteral struct {
+	Token token.Token // the 'null' token
+}
+
+// expressionNode receives NullLiteral to create an AST node
+func (nl *NullLiteral) expressionNode() {}
+
+// TokenLiteral receives NullLiteral for tokenization
+func (nl *NullLiteral) TokenLiteral() string {
+	return nl.Token.Literal
+}
+
+// String returns the null keyword
+func (nl *NullLiteral) String() string {
+	return nl.Token.Literal
+}
+
+// SequenceExpression structure for a parenthesized, comma separated group of expressions, (a, b, c). Each is evaluated in order and the last value is the result.
+type SequenceExpression struct {
+	Token       token.Token // the '(' token
+	Expressions []Expression
+}
+
+// expressionNode receives SequenceExpression to create an AST node
+func (se *SequenceExpression) expressionNode() {}
+
+// TokenLiteral receives SequenceExpression for tokenization
+func (se *SequenceExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+
+// String writes the sequence's expressions separated by commas, enclosed by parentheses
+func (se *SequenceExpression) String() string {
+	var out bytes.Buffer
+
+	expressions := []string{}
+
+	for _, e := range se.Expressions {
+		expressions = append(expressions, e.String())
+	}
+
+	out.WriteString("(")
+	out.WriteString(strings.Join(expressions, ", "))
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// LetInExpression structure for a let-in expression, let x = 5 in x * 2. The name is only bound while evaluating the body.
+type LetInExpression struct {
+	Token token.Token // the token.LET token
+	Name  *Identifier
+	Type  *Identifier // optional type annotation, nil when there isn't one
+	Value Expression
+	Body  Expression
+}
+
+// expressionNode receives LetInExpression to create an AST node
+func (le *LetInExpression) expressionNode() {}
+
+// TokenLiteral receives LetInExpression for tokenization
+func (le *LetInExpression) TokenLiteral() string {
+	return le.Token.Literal
+}
+
+// String writes the let-in expression in parentheses like other compound expressions, (let x = 5 in (x * 2)), so (let x = 5 in x) + 1 doesn't read as a let whose body is x + 1
+func (le *LetInExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(" + le.TokenLiteral() + " ")
+	out.WriteString(le.Name.String())
+
+	if le.Type != nil {
+		out.WriteString(": " + le.Type.String())
+	}
+
+	out.WriteString(" = ")
+	out.WriteString(le.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(le.Body.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// AssignExpression structure for updating an existing binding, x = x + 1, or an element of an array or hash, arr[0] = 5
+type AssignExpression struct {
+	Token token.Token      // the '=' token
+	Name  *Identifier      // the binding assigned to, nil when Index is set
+	Index *IndexExpression // the element assigned to, nil when Name is set
+	Value Expression
+}
+
+// expressionNode receives AssignExpression to create an AST node
+func (ae *AssignExpression) expressionNode() {}
+
+// TokenLiteral receives AssignExpression for tokenization
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+
+// String writes the assignment, (x = (x + 1))
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+
+	if ae.Index != nil {
+		out.WriteString(ae.Index.String())
+	} else {
+		out.WriteString(ae.Name.String())
+	}
+
+	out.WriteString(" = ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// TernaryExpression structure for a conditional expression, cond ? a : b
+type TernaryExpression struct {
+	Token       token.Token // the '?' token
+	Condition   Expression
+	Consequence Expression // the value when the condition is truthy
+	Alternative Expression // the value otherwise
+}
+
+// expressionNode receives TernaryExpression to create an AST node
+func (te *TernaryExpression) expressionNode() {}
+
+// TokenLiteral receives TernaryExpression for tokenization
+func (te *TernaryExpression) TokenLiteral() string {
+	return te.Token.Literal
+}
+
+// String writes the ternary, (c ? a : b)
+func (te *TernaryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(te.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(te.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(te.Alternative.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 // IfExpression structure for If statements
 type IfExpression struct {
 	Token       token.Token     // The 'if' token
@@ -306,6 +520,33 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+// WhileExpression structure for while loops
+type WhileExpression struct {
+	Token     token.Token     // The 'while' token
+	Condition Expression      // The loop runs while the condition is truthy
+	Body      *BlockStatement // Evaluated on every pass through the loop
+}
+
+// expressionNode receives the WhileExpression to create an AST node
+func (we *WhileExpression) expressionNode() {}
+
+// TokenLiteral receives the WhileExpression to tokenize
+func (we *WhileExpression) TokenLiteral() string {
+	return we.Token.Literal
+}
+
+// String receives the WhileExpression for documentation and testing
+func (we *WhileExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while")
+	out.WriteString(we.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(we.Body.String())
+
+	return out.String()
+}
+
 // BlockStatement is a structure for consequences and alternatives of If statements
 type BlockStatement struct {
 	Token      token.Token // The { token
@@ -333,9 +574,10 @@ func (bs *BlockStatement) String() string {
 
 // FunctionLiteral structure defines a function
 type FunctionLiteral struct {
-	Token      token.Token     // The 'fn' token
-	Parameters []*Identifier   // Function parameters (a,b,c)
-	Body       *BlockStatement // Function statement
+	Token      token.Token           // The 'fn' token
+	Parameters []*Identifier         // Function parameters (a,b,c)
+	Defaults   map[string]Expression // Default values by parameter name, fn(x, y = 10), nil when no parameter has one
+	Body       *BlockStatement       // Function statement
 }
 
 // expressionNode assign an AST node to FunctionLiteral
@@ -353,7 +595,7 @@ func (fl *FunctionLiteral) String() string {
 	params := []string{}
 
 	for _, p := range fl.Parameters { // *ast.Identifiers
-		params = append(params, p.String())
+		params = append(params, ParameterString(p, fl.Defaults))
 	}
 
 	out.WriteString(fl.TokenLiteral())
@@ -365,6 +607,35 @@ func (fl *FunctionLiteral) String() string {
 	return out.String()
 }
 
+// NamedArgument structure for an argument passed by parameter name, the y = 3 in f(x, y = 3). Named arguments come after any positional arguments and bind to the parameter with the same name, in any order.
+type NamedArgument struct {
+	Token token.Token // the parameter name token
+	Name  *Identifier
+	Value Expression
+}
+
+// expressionNode receives NamedArgument to create an AST node
+func (na *NamedArgument) expressionNode() {}
+
+// TokenLiteral receives NamedArgument for tokenization
+func (na *NamedArgument) TokenLiteral() string {
+	return na.Token.Literal
+}
+
+// String writes the named argument, y = 3
+func (na *NamedArgument) String() string {
+	return na.Name.String() + " = " + na.Value.String()
+}
+
+// ParameterString writes a function parameter with its default value, if it has one, y = 10
+func ParameterString(param *Identifier, defaults map[string]Expression) string {
+	if def, ok := defaults[param.Value]; ok {
+		return param.String() + " = " + def.String()
+	}
+
+	return param.String()
+}
+
 // CallExpression structure for Call Expression AST Node, DoorKey example: 'add(2, 3)' , or 'callsFunction(2, 3, fn(x + y) {x + y;};' , 'out.WriteString(strings.Join(args, ","))' # Golang expression call
 type CallExpression struct {
 	Token     token.Token  // The '(' token
@@ -429,11 +700,82 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// ArrayComprehension structure for building an array from a collection, [x * 2 for x in arr if x > 1]
+type ArrayComprehension struct {
+	Token      token.Token // the '[' token
+	Element    Expression  // evaluated for each item to build the array
+	Variable   *Identifier // bound to each item of the collection in turn
+	Collection Expression
+	Condition  Expression // Optional filter, items it's falsy for are skipped
+}
+
+// ExpressionNode creates an AST expression node for ArrayComprehensions
+func (ac *ArrayComprehension) expressionNode() {}
+
+// TokenLiteral returns the token value for array comprehension
+func (ac *ArrayComprehension) TokenLiteral() string {
+	return ac.Token.Literal
+}
+
+// String returns the comprehension as a string, [(x * 2) for x in arr if (x > 1)]
+func (ac *ArrayComprehension) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("[")
+	out.WriteString(ac.Element.String())
+	out.WriteString(comprehensionClauseString(ac.Variable, ac.Collection, ac.Condition))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// HashComprehension structure for building a hash from a collection, {x: x * x for x in arr if x > 1}
+type HashComprehension struct {
+	Token      token.Token // the '{' token
+	Key        Expression  // evaluated for each item, with Value, to build a pair
+	Value      Expression
+	Variable   *Identifier // bound to each item of the collection in turn
+	Collection Expression
+	Condition  Expression // Optional filter, items it's falsy for are skipped
+}
+
+// ExpressionNode creates an AST expression node for HashComprehensions
+func (hc *HashComprehension) expressionNode() {}
+
+// TokenLiteral returns the token value for hash comprehension
+func (hc *HashComprehension) TokenLiteral() string {
+	return hc.Token.Literal
+}
+
+// String returns the comprehension as a string, {x:(x * x) for x in arr if (x > 1)}
+func (hc *HashComprehension) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("{")
+	out.WriteString(hc.Key.String() + ":" + hc.Value.String())
+	out.WriteString(comprehensionClauseString(hc.Variable, hc.Collection, hc.Condition))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// comprehensionClauseString returns the " for x in arr if cond" part of a comprehension as a string
+func comprehensionClauseString(variable *Identifier, collection, condition Expression) string {
+	out := " for " + variable.String() + " in " + collection.String()
+
+	if condition != nil {
+		out += " if " + condition.String()
+	}
+
+	return out
+}
+
 // IndexExpression structure for array index expressions
 type IndexExpression struct {
-	Token token.Token // The [ token
-	Left  Expression
-	Index Expression
+	Token   token.Token // The [ token
+	Left    Expression
+	Index   Expression
+	Default Expression // Optional value returned when the index is missing, hash["key", default]
 }
 
 // expressionNode receives an IndexExpression for an AST node
@@ -452,6 +794,51 @@ func (ie *IndexExpression) String() string {
 	out.WriteString(ie.Left.String())
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
+
+	if ie.Default != nil {
+		out.WriteString(", ")
+		out.WriteString(ie.Default.String())
+	}
+
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// SliceExpression structure for array slices, arr[low:high]. Low or High is nil when it is left out.
+type SliceExpression struct {
+	Token token.Token // The [ token
+	Left  Expression
+	Low   Expression
+	High  Expression
+}
+
+// expressionNode receives a SliceExpression for an AST node
+func (se *SliceExpression) expressionNode() {}
+
+// TokenLiteral receives a SliceExpression for a token
+func (se *SliceExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+
+// String writes the slice as "(LeftExp[low:high])", leaving out missing bounds
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+
+	if se.Low != nil {
+		out.WriteString(se.Low.String())
+	}
+
+	out.WriteString(":")
+
+	if se.High != nil {
+		out.WriteString(se.High.String())
+	}
+
 	out.WriteString("])")
 
 	return out.String()
@@ -461,6 +848,21 @@ func (ie *IndexExpression) String() string {
 type HashLiteral struct {
 	Token token.Token // the '{' token
 	Pairs map[Expression]Expression
+	Order []Expression // the keys of Pairs in the order they appear in the source
+}
+
+// Keys returns the keys of the hash literal in source order. A literal built without Order gives its keys in map order.
+func (hl *HashLiteral) Keys() []Expression {
+	if len(hl.Order) == len(hl.Pairs) {
+		return hl.Order
+	}
+
+	keys := make([]Expression, 0, len(hl.Pairs))
+	for key := range hl.Pairs {
+		keys = append(keys, key)
+	}
+
+	return keys
 }
 
 // expressionNode creates a HashLiteral AST expression node
@@ -477,8 +879,8 @@ func (hl *HashLiteral) String() string {
 
 	pairs := []string{}
 
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range hl.Keys() {
+		pairs = append(pairs, key.String()+":"+hl.Pairs[key].String())
 	}
 
 	out.WriteString("{")
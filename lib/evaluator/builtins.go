@@ -9,23 +9,86 @@ package evaluator
 
 import (
 	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/tmoore2016/interpreter/lib/object"
 )
 
-// Separate Builtins environment, allowing builtin Go functions to be called through Doorkey.
-var builtins = map[string]*object.Builtin{
+// Version is the Doorkey interpreter version reported by version()
+const Version = "0.1.0"
+
+// Sandbox disables builtins that reach outside of the interpreter, such as readLine, for embedding untrusted scripts
+var Sandbox = false
+
+// envBuiltins are builtins that use the input and output of the environment they're looked up in (object.Environment.SetIO), so each is made once per input and output (object.Environment.IOBuiltin) rather than shared
+var envBuiltins = map[string]func(env *object.Environment) *object.Builtin{
 
 	// puts function allows Doorkey to print to terminal
-	"puts": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
-			for _, arg := range args {
-				fmt.Println(arg.Inspect())
-			}
+	"puts": func(env *object.Environment) *object.Builtin {
+		return &object.Builtin{
+			Fn: func(args ...object.Object) object.Object {
+				out := env.Output()
 
-			return NULL
-		},
+				for _, arg := range args {
+					fmt.Fprintln(out, arg.Inspect())
+				}
+
+				return NULL
+			},
+		}
+	},
+
+	// readLine() reads one line from the interpreter's input without the trailing newline, or returns null at the end of input
+	"readLine": func(env *object.Environment) *object.Builtin {
+		return &object.Builtin{
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 0 {
+					return newError("wrong number of arguments. got=%d, want=0", len(args))
+				}
+
+				if Sandbox {
+					return newError("'readLine' is not available in sandbox mode")
+				}
+
+				line, err := env.Input().ReadString('\n')
+
+				if err == io.EOF && line == "" {
+					return NULL
+				}
+
+				if err != nil && err != io.EOF {
+					return newError("could not read input: %s", err)
+				}
+
+				line = strings.TrimSuffix(line, "\n")
+				line = strings.TrimSuffix(line, "\r")
+
+				return &object.String{Value: line}
+			},
+		}
 	},
+}
+
+// lookupBuiltin returns the builtin function with the given name, the one made for env's input and output if it uses them
+func lookupBuiltin(name string, env *object.Environment) (*object.Builtin, bool) {
+	if makeBuiltin, ok := envBuiltins[name]; ok {
+		return env.IOBuiltin(name, makeBuiltin), true
+	}
+
+	builtin, ok := builtins[name]
+
+	return builtin, ok
+}
+
+// Separate Builtins environment, allowing builtin Go functions to be called through Doorkey.
+var builtins = map[string]*object.Builtin{
 
 	// length (len) function for counting characters in a string
 	"len": &object.Builtin{
@@ -39,9 +102,9 @@ var builtins = map[string]*object.Builtin{
 			// If object type is array, length will return the number of elements as an integer
 			case *object.Array:
 				return &object.Integer{Value: int64(len(arg.Elements))}
-			// If object evaluated is type string, length will return the number of characters
+			// If object evaluated is type string, length will return the number of characters (runes, not bytes)
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 			// In all other cases return an error
 			default:
 				return newError("argument to 'len' not supported, got %s", args[0].Type())
@@ -130,6 +193,10 @@ var builtins = map[string]*object.Builtin{
 			arr := args[0].(*object.Array)
 			length := len(arr.Elements)
 
+			if err := checkArrayLength(length + 1); err != nil {
+				return err
+			}
+
 			newElements := make([]object.Object, length+1, length+1)
 			copy(newElements, arr.Elements)
 			newElements[length] = args[1]
@@ -137,4 +204,1360 @@ var builtins = map[string]*object.Builtin{
 			return &object.Array{Elements: newElements}
 		},
 	},
+
+	// reverse() returns a new array containing the elements of the input array in reverse order
+	"reverse": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError("argument to 'reverse' must be an ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*object.Array)
+			length := len(arr.Elements)
+
+			newElements := make([]object.Object, length)
+			for i, el := range arr.Elements {
+				newElements[length-1-i] = el
+			}
+
+			return &object.Array{Elements: newElements}
+		},
+	},
+
+	// concatStrings() joins an array of strings into one string with a single allocation. Building a string with + in a loop copies the whole string on every step, so this is the fast alternative.
+	"concatStrings": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError("argument to 'concatStrings' must be an ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*object.Array)
+
+			// Total the length first so the builder allocates once
+			length := 0
+
+			for i, el := range arr.Elements {
+				str, ok := el.(*object.String)
+				if !ok {
+					return newError("element %d of 'concatStrings' must be a STRING, got %s", i, el.Type())
+				}
+
+				length += len(str.Value)
+
+				if err := checkStringLength(length); err != nil {
+					return err
+				}
+			}
+
+			var out strings.Builder
+			out.Grow(length)
+
+			for _, el := range arr.Elements {
+				out.WriteString(el.(*object.String).Value)
+			}
+
+			return &object.String{Value: out.String()}
+		},
+	},
+
+	// chars() splits a string into an array of single character strings, one per rune
+	"chars": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			if args[0].Type() != object.STRING_OBJ {
+				return newError("argument to 'chars' must be a STRING, got %s", args[0].Type())
+			}
+
+			str := args[0].(*object.String).Value
+
+			if err := checkArrayLength(utf8.RuneCountInString(str)); err != nil {
+				return err
+			}
+
+			elements := make([]object.Object, 0, len(str))
+
+			for _, r := range str {
+				elements = append(elements, &object.String{Value: string(r)})
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	// startsWith() returns true if the first string begins with the prefix string
+	"startsWith": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			if args[0].Type() != object.STRING_OBJ || args[1].Type() != object.STRING_OBJ {
+				return newError("arguments to 'startsWith' must be STRING, got %s and %s", args[0].Type(), args[1].Type())
+			}
+
+			str := args[0].(*object.String).Value
+			prefix := args[1].(*object.String).Value
+
+			return nativeBoolToBooleanObject(strings.HasPrefix(str, prefix))
+		},
+	},
+
+	// endsWith() returns true if the first string ends with the suffix string
+	"endsWith": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			if args[0].Type() != object.STRING_OBJ || args[1].Type() != object.STRING_OBJ {
+				return newError("arguments to 'endsWith' must be STRING, got %s and %s", args[0].Type(), args[1].Type())
+			}
+
+			str := args[0].(*object.String).Value
+			suffix := args[1].(*object.String).Value
+
+			return nativeBoolToBooleanObject(strings.HasSuffix(str, suffix))
+		},
+	},
+
+	// padLeft() pads the start of a string with a single character fill string until it is at least width characters long
+	"padLeft": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return padString("padLeft", true, args...)
+		},
+	},
+
+	// padRight() pads the end of a string with a single character fill string until it is at least width characters long
+	"padRight": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return padString("padRight", false, args...)
+		},
+	},
+
+	// strRepeat() returns a string repeated n times
+	"strRepeat": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("first argument to 'strRepeat' must be a STRING, got %s", args[0].Type())
+			}
+
+			count, ok := args[1].(*object.Integer)
+			if !ok {
+				return newError("second argument to 'strRepeat' must be an INTEGER, got %s", args[1].Type())
+			}
+
+			if count.Value < 0 {
+				return newError("second argument to 'strRepeat' must not be negative, got %d", count.Value)
+			}
+
+			// Past this check the count fits in an int
+			if err := checkStringLength(repeatedLength(0, len(str.Value), count.Value)); err != nil {
+				return err
+			}
+
+			return &object.String{Value: strings.Repeat(str.Value, int(count.Value))}
+		},
+	},
+
+	// clamp() bounds an integer value to the range [lo, hi]
+	"clamp": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=3", len(args))
+			}
+
+			for _, arg := range args {
+				if arg.Type() != object.INTEGER_OBJ {
+					return newError("arguments to 'clamp' must be INTEGER, got %s", arg.Type())
+				}
+			}
+
+			value := args[0].(*object.Integer)
+			lo := args[1].(*object.Integer)
+			hi := args[2].(*object.Integer)
+
+			if lo.Value > hi.Value {
+				return newError("invalid bounds for 'clamp', lo=%d is greater than hi=%d", lo.Value, hi.Value)
+			}
+
+			if value.Value < lo.Value {
+				return lo
+			}
+
+			if value.Value > hi.Value {
+				return hi
+			}
+
+			return value
+		},
+	},
+
+	// gcd() returns the greatest common divisor of two integers, gcd(0, 0) is 0
+	"gcd": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			a, b, err := integerPair("gcd", args...)
+			if err != nil {
+				return err
+			}
+
+			divisor := gcd(a, b)
+			if divisor > math.MaxInt64 {
+				return newError("integer overflow in 'gcd'")
+			}
+
+			return &object.Integer{Value: int64(divisor)}
+		},
+	},
+
+	// lcm() returns the least common multiple of two integers, lcm with 0 is 0
+	"lcm": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			a, b, err := integerPair("lcm", args...)
+			if err != nil {
+				return err
+			}
+
+			if a == 0 || b == 0 {
+				return &object.Integer{Value: 0}
+			}
+
+			x, y := absUint(a), absUint(b)
+			quotient := x / gcd(a, b)
+			multiple := quotient * y
+
+			// Guard against the product wrapping around or exceeding int64
+			if multiple/y != quotient || multiple > math.MaxInt64 {
+				return newError("integer overflow in 'lcm'")
+			}
+
+			return &object.Integer{Value: int64(multiple)}
+		},
+	},
+
+	// setOf() builds a set from an array, duplicates are dropped. Sets are hashes whose keys are the elements and whose values are all true, so set[x] is true for members and null otherwise.
+	"setOf": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to 'setOf' must be an ARRAY, got %s", args[0].Type())
+			}
+
+			set := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+
+			for _, el := range arr.Elements {
+				hashable, ok := el.(object.Hashable)
+				if !ok {
+					return newError("Unusable as hash key: %s", el.Type())
+				}
+
+				set.Set(hashable.HashKey(), object.HashPair{Key: el, Value: TRUE})
+			}
+
+			return set
+		},
+	},
+
+	// union() returns a set of the elements in either set
+	"union": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return setOperation("union", func(inA, inB bool) bool { return inA || inB }, args...)
+		},
+	},
+
+	// intersect() returns a set of the elements in both sets
+	"intersect": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return setOperation("intersect", func(inA, inB bool) bool { return inA && inB }, args...)
+		},
+	},
+
+	// difference() returns a set of the elements in the first set but not the second
+	"difference": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return setOperation("difference", func(inA, inB bool) bool { return inA && !inB }, args...)
+		},
+	},
+
+	// describe() returns a hash of metadata about a value: its type, plus its length for arrays, strings, and hashes
+	"describe": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			keys := []string{"type"}
+			values := []object.Object{&object.String{Value: string(args[0].Type())}}
+
+			var length int
+
+			switch arg := args[0].(type) {
+			case *object.Array:
+				length = len(arg.Elements)
+			case *object.String:
+				length = utf8.RuneCountInString(arg.Value)
+			case *object.Hash:
+				length = len(arg.Pairs)
+			default:
+				return stringKeyHash(keys, values)
+			}
+
+			keys = append(keys, "length")
+			values = append(values, &object.Integer{Value: int64(length)})
+
+			return stringKeyHash(keys, values)
+		},
+	},
+
+	// slice() returns a new array of the elements from start up to, but not including, end. end defaults to the length of the array, negative indices count from the end and out of range indices are clamped.
+	"slice": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("first argument to 'slice' must be an ARRAY, got %s", args[0].Type())
+			}
+
+			start, end, err := sliceBounds("slice", len(arr.Elements), args[1:])
+			if err != nil {
+				return err
+			}
+
+			return &object.Array{Elements: copyElements(arr.Elements[start:end])}
+		},
+	},
+
+	// substr() returns the part of a string from start up to, but not including, end. Indices count characters, like len(), and work like slice().
+	"substr": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("first argument to 'substr' must be a STRING, got %s", args[0].Type())
+			}
+
+			chars := []rune(str.Value)
+
+			start, end, err := sliceBounds("substr", len(chars), args[1:])
+			if err != nil {
+				return err
+			}
+
+			return &object.String{Value: string(chars[start:end])}
+		},
+	},
+
+	// take() returns a new array of the first n elements, a negative n leaves off the last -n elements
+	"take": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			arr, n, err := arrayCount("take", args...)
+			if err != nil {
+				return err
+			}
+
+			return &object.Array{Elements: copyElements(arr.Elements[:normalizeIndex(n, len(arr.Elements))])}
+		},
+	},
+
+	// drop() returns a new array without the first n elements, a negative n keeps only the last -n elements
+	"drop": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			arr, n, err := arrayCount("drop", args...)
+			if err != nil {
+				return err
+			}
+
+			return &object.Array{Elements: copyElements(arr.Elements[normalizeIndex(n, len(arr.Elements)):])}
+		},
+	},
+
+	// str() converts a value to a string of its Inspect() representation, str(42) is "42"
+	"str": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			if str, ok := args[0].(*object.String); ok {
+				return str
+			}
+
+			return &object.String{Value: args[0].Inspect()}
+		},
+	},
+
+	// int() converts a string of digits to an integer, int("123") is 123. Integers are returned unchanged and floats are truncated toward zero, or an error if they're NaN, infinite or out of range.
+	"int": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				return arg
+			case *object.Float:
+				// Go's conversion gives an arbitrary result for these, so they're errors. MaxInt64 rounds up to 2^63 as a float, which is out of range.
+				if math.IsNaN(arg.Value) || arg.Value < math.MinInt64 || arg.Value >= math.MaxInt64 {
+					return newError("could not convert %s to integer", arg.Inspect())
+				}
+
+				return &object.Integer{Value: int64(arg.Value)}
+			case *object.String:
+				value, err := strconv.ParseInt(arg.Value, 10, 64)
+				if err != nil {
+					return newError("could not parse %q as integer", arg.Value)
+				}
+
+				return &object.Integer{Value: value}
+			default:
+				return newError("argument to 'int' not supported, got %s", args[0].Type())
+			}
+		},
+	},
+
+	// version() returns a hash of the interpreter version and the Go version it was built with, for bug reports
+	"version": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return newError("wrong number of arguments. got=%d, want=0", len(args))
+			}
+
+			return stringKeyHash(
+				[]string{"version", "go"},
+				[]object.Object{&object.String{Value: Version}, &object.String{Value: runtime.Version()}},
+			)
+		},
+	},
+
+	// with() returns a deep copy of a hash with one key set to a new value. Nested arrays and hashes are copied too, so the new hash shares nothing mutable with the original.
+	"with": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=3", len(args))
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("first argument to 'with' must be a HASH, got %s", args[0].Type())
+			}
+
+			key, ok := args[1].(object.Hashable)
+			if !ok {
+				return newError("Unusable as hash key: %s", args[1].Type())
+			}
+
+			updated := deepCopy(hash).(*object.Hash)
+			updated.Set(key.HashKey(), object.HashPair{Key: args[1], Value: deepCopy(args[2])})
+
+			return updated
+		},
+	},
+
+	// inspect() returns the Inspect() string of a value like str(), except that strings are quoted, so inspect("a") is "\"a\"" while str("a") is "a"
+	"inspect": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			if str, ok := args[0].(*object.String); ok {
+				return &object.String{Value: strconv.Quote(str.Value)}
+			}
+
+			return &object.String{Value: args[0].Inspect()}
+		},
+	},
+
+	// filled() returns a new array the same length as the input array with every element replaced by the value
+	"filled": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("first argument to 'filled' must be an ARRAY, got %s", args[0].Type())
+			}
+
+			elements := make([]object.Object, len(arr.Elements))
+			for i := range elements {
+				elements[i] = args[1]
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	// pushAll() returns a new array of the input array's elements followed by the other arguments, pushAll([1], 2, 3) is [1, 2, 3]
+	"pushAll": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 {
+				return newError("wrong number of arguments. got=%d, want=at least 1", len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("first argument to 'pushAll' must be an ARRAY, got %s", args[0].Type())
+			}
+
+			if err := checkArrayLength(len(arr.Elements) + len(args) - 1); err != nil {
+				return err
+			}
+
+			newElements := make([]object.Object, 0, len(arr.Elements)+len(args)-1)
+			newElements = append(newElements, arr.Elements...)
+			newElements = append(newElements, args[1:]...)
+
+			return &object.Array{Elements: newElements}
+		},
+	},
+
+	// toJson() encodes a value as a JSON string, functions can't be encoded
+	"toJson": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			encoded, err := object.ToJSON(args[0])
+			if err != nil {
+				return newError("argument to 'toJson' %s", err)
+			}
+
+			return &object.String{Value: encoded}
+		},
+	},
+
+	// keys() returns an array of a hash's keys, in insertion order
+	"keys": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("argument to 'keys' must be a HASH, got %s", args[0].Type())
+			}
+
+			if err := checkArrayLength(len(hash.Pairs)); err != nil {
+				return err
+			}
+
+			pairs := hash.OrderedPairs()
+			elements := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				elements[i] = pair.Key
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	// values() returns an array of a hash's values, in insertion order
+	"values": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("argument to 'values' must be a HASH, got %s", args[0].Type())
+			}
+
+			// In the same order as keys, so values(h)[i] belongs to keys(h)[i]
+			if err := checkArrayLength(len(hash.Pairs)); err != nil {
+				return err
+			}
+
+			pairs := hash.OrderedPairs()
+			elements := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				elements[i] = pair.Value
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	// split() breaks a string apart at each separator, an empty separator splits it into characters
+	"split": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("first argument to 'split' must be a STRING, got %s", args[0].Type())
+			}
+
+			sep, ok := args[1].(*object.String)
+			if !ok {
+				return newError("second argument to 'split' must be a STRING, got %s", args[1].Type())
+			}
+
+			parts := strings.Split(str.Value, sep.Value)
+			if err := checkArrayLength(len(parts)); err != nil {
+				return err
+			}
+
+			elements := make([]object.Object, len(parts))
+			for i, part := range parts {
+				elements[i] = &object.String{Value: part}
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+
+	// contains() reports whether a string contains a substring, or an array contains an element equal to the value (object.Equals)
+	"contains": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			switch haystack := args[0].(type) {
+			case *object.String:
+				needle, ok := args[1].(*object.String)
+				if !ok {
+					return newError("second argument to 'contains' must be a STRING for a STRING, got %s", args[1].Type())
+				}
+
+				return nativeBoolToBooleanObject(strings.Contains(haystack.Value, needle.Value))
+
+			case *object.Array:
+				for _, el := range haystack.Elements {
+					if object.Equals(el, args[1]) {
+						return TRUE
+					}
+				}
+
+				return FALSE
+
+			default:
+				return newError("first argument to 'contains' must be a STRING or ARRAY, got %s", args[0].Type())
+			}
+		},
+	},
+}
+
+// padString validates the (string, width, fill) arguments of padLeft and padRight and pads the string on the chosen side. Strings already at least width characters long are returned unchanged, nothing is truncated.
+func padString(name string, left bool, args ...object.Object) object.Object {
+	if len(args) != 3 {
+		return newError("wrong number of arguments. got=%d, want=3", len(args))
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newError("first argument to '%s' must be a STRING, got %s", name, args[0].Type())
+	}
+
+	width, ok := args[1].(*object.Integer)
+	if !ok {
+		return newError("second argument to '%s' must be an INTEGER, got %s", name, args[1].Type())
+	}
+
+	fill, ok := args[2].(*object.String)
+	if !ok || utf8.RuneCountInString(fill.Value) != 1 {
+		return newError("third argument to '%s' must be a single character STRING, got %s", name, args[2].Inspect())
+	}
+
+	missing := width.Value - int64(utf8.RuneCountInString(str.Value))
+	if missing <= 0 {
+		return str
+	}
+
+	// The fill character can be several bytes, so the limit is checked against bytes rather than the rune count. Past this check missing fits in an int.
+	if err := checkStringLength(repeatedLength(len(str.Value), len(fill.Value), missing)); err != nil {
+		return err
+	}
+
+	padding := strings.Repeat(fill.Value, int(missing))
+
+	if left {
+		return &object.String{Value: padding + str.Value}
+	}
+
+	return &object.String{Value: str.Value + padding}
+}
+
+// integerPair validates that a builtin received exactly two integer arguments and returns their values
+func integerPair(name string, args ...object.Object) (int64, int64, *object.Error) {
+	if len(args) != 2 {
+		return 0, 0, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	a, ok := args[0].(*object.Integer)
+	if !ok {
+		return 0, 0, newError("arguments to '%s' must be INTEGER, got %s", name, args[0].Type())
+	}
+
+	b, ok := args[1].(*object.Integer)
+	if !ok {
+		return 0, 0, newError("arguments to '%s' must be INTEGER, got %s", name, args[1].Type())
+	}
+
+	return a.Value, b.Value, nil
+}
+
+// gcd uses Euclid's algorithm on the absolute values of a and b. It works in uint64 so the absolute value of math.MinInt64 doesn't overflow.
+func gcd(a, b int64) uint64 {
+	x, y := absUint(a), absUint(b)
+
+	for y != 0 {
+		x, y = y, x%y
+	}
+
+	return x
+}
+
+// absUint returns the absolute value of n as a uint64
+func absUint(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+
+	return uint64(n)
+}
+
+// setOperation builds a new set from the keys of two set-hashes, keeping each key for which keep(inA, inB) is true
+func setOperation(name string, keep func(inA, inB bool) bool, args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	a, ok := args[0].(*object.Hash)
+	if !ok {
+		return newError("arguments to '%s' must be HASH, got %s", name, args[0].Type())
+	}
+
+	b, ok := args[1].(*object.Hash)
+	if !ok {
+		return newError("arguments to '%s' must be HASH, got %s", name, args[1].Type())
+	}
+
+	set := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+
+	for _, source := range []*object.Hash{a, b} {
+		for _, pair := range source.OrderedPairs() {
+			hashKey := pair.Key.(object.Hashable).HashKey()
+			_, inA := a.Pairs[hashKey]
+			_, inB := b.Pairs[hashKey]
+
+			if keep(inA, inB) {
+				set.Set(hashKey, object.HashPair{Key: pair.Key, Value: TRUE})
+			}
+		}
+	}
+
+	return set
+}
+
+// init registers the builtins that call back into the evaluator through applyFunction. They can't be part of the builtins literal because applyFunction refers back to builtins, which Go rejects as an initialization cycle.
+func init() {
+
+	// flatMap() applies a function returning an array to each element of an array, and concatenates the results into one array
+	builtins["flatMap"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("first argument to 'flatMap' must be an ARRAY, got %s", args[0].Type())
+			}
+
+			if !isCallable(args[1]) {
+				return newError("second argument to 'flatMap' must be a FUNCTION, got %s", args[1].Type())
+			}
+
+			elements := []object.Object{}
+
+			for _, el := range arr.Elements {
+				result := applyFunction(args[1], []object.Object{el})
+				if isError(result) {
+					return result
+				}
+
+				mapped, ok := result.(*object.Array)
+				if !ok {
+					return newError("function passed to 'flatMap' must return an ARRAY, got %s", typeOf(result))
+				}
+
+				if err := checkArrayLength(len(elements) + len(mapped.Elements)); err != nil {
+					return err
+				}
+
+				elements = append(elements, mapped.Elements...)
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	}
+
+	// filter() returns a new array of the elements of an array for which the function returns a truthy value
+	builtins["filter"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to 'filter' must be an ARRAY, got %s", args[0].Type())
+			}
+
+			if !isCallable(args[1]) {
+				return newError("second argument to 'filter' must be a FUNCTION, got %s", args[1].Type())
+			}
+
+			elements := []object.Object{}
+
+			for _, el := range arr.Elements {
+				result := applyFunction(args[1], []object.Object{el})
+				if isError(result) {
+					return result
+				}
+
+				// A function ending in a let statement returns nothing, which counts as NULL
+				if result != nil && isTruthy(result) {
+					elements = append(elements, el)
+				}
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	}
+
+	// reduce() folds an array into one value, calling the function with the accumulator and each element in turn. The initial value is returned for an empty array.
+	builtins["reduce"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=3", len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("first argument to 'reduce' must be an ARRAY, got %s", args[0].Type())
+			}
+
+			if !isCallable(args[2]) {
+				return newError("third argument to 'reduce' must be a FUNCTION, got %s", args[2].Type())
+			}
+
+			acc := args[1]
+
+			for _, el := range arr.Elements {
+				acc = applyFunction(args[2], []object.Object{acc, el})
+				if isError(acc) {
+					return acc
+				}
+
+				if acc == nil {
+					acc = NULL
+				}
+			}
+
+			return acc
+		},
+	}
+
+	// apply() calls a function with the elements of an array as its arguments, like JavaScript's Function.apply
+	builtins["apply"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+
+			if !isCallable(args[0]) {
+				return newError("first argument to 'apply' must be a FUNCTION, got %s", args[0].Type())
+			}
+
+			arr, ok := args[1].(*object.Array)
+			if !ok {
+				return newError("second argument to 'apply' must be an ARRAY, got %s", args[1].Type())
+			}
+
+			// Unlike a direct call, too few arguments is an error rather than a partial application. Parameters with defaults can be left out.
+			if fn, ok := args[0].(*object.Function); ok {
+				if len(arr.Elements) < requiredParameters(fn) {
+					return newError("wrong number of arguments to 'apply'. got=%d, want=%d", len(arr.Elements), requiredParameters(fn))
+				}
+
+				if len(arr.Elements) > len(fn.Parameters) {
+					return newError("wrong number of arguments to 'apply'. got=%d, want=%d", len(arr.Elements), len(fn.Parameters))
+				}
+			}
+
+			return applyFunction(args[0], arr.Elements)
+		},
+	}
+
+	// timeit() calls a function with no arguments and returns a hash of its result and how long the call took in milliseconds, {"result": 5, "ms": 0.012}
+	builtins["timeit"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+
+			if Sandbox {
+				return newError("'timeit' is not available in sandbox mode")
+			}
+
+			if !isCallable(args[0]) {
+				return newError("argument to 'timeit' must be a FUNCTION, got %s", args[0].Type())
+			}
+
+			start := time.Now()
+			result := applyFunction(args[0], []object.Object{})
+			elapsed := time.Since(start)
+
+			if isError(result) {
+				return result
+			}
+
+			if result == nil {
+				result = NULL
+			}
+
+			ms := &object.Float{Value: float64(elapsed) / float64(time.Millisecond)}
+
+			return stringKeyHash([]string{"result", "ms"}, []object.Object{result, ms})
+		},
+	}
+
+	// builtin() returns the original builtin function with the given name, even when the name has been shadowed by a let statement
+	envBuiltins["builtin"] = func(env *object.Environment) *object.Builtin {
+		return &object.Builtin{
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+
+				name, ok := args[0].(*object.String)
+				if !ok {
+					return newError("argument to 'builtin' must be a STRING, got %s", args[0].Type())
+				}
+
+				if fn, ok := lookupBuiltin(name.Value, env); ok {
+					return fn
+				}
+
+				return newError("no builtin function named %q", name.Value)
+			},
+		}
+	}
+
+	// find() returns the first element of an array the function returns a truthy value for, or null if there isn't one. Later elements aren't tested.
+	builtins["find"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			arr, err := predicateArguments("find", args)
+			if err != nil {
+				return err
+			}
+
+			for _, el := range arr.Elements {
+				matched, err := testPredicate(args[1], el)
+				if err != nil {
+					return err
+				}
+
+				if matched {
+					return el
+				}
+			}
+
+			return NULL
+		},
+	}
+
+	// every() returns true if the function returns a truthy value for every element of an array, stopping at the first falsy one. An empty array gives true.
+	builtins["every"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			arr, err := predicateArguments("every", args)
+			if err != nil {
+				return err
+			}
+
+			for _, el := range arr.Elements {
+				matched, err := testPredicate(args[1], el)
+				if err != nil {
+					return err
+				}
+
+				if !matched {
+					return FALSE
+				}
+			}
+
+			return TRUE
+		},
+	}
+
+	// some() returns true if the function returns a truthy value for any element of an array, stopping at the first one. An empty array gives false.
+	builtins["some"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			arr, err := predicateArguments("some", args)
+			if err != nil {
+				return err
+			}
+
+			for _, el := range arr.Elements {
+				matched, err := testPredicate(args[1], el)
+				if err != nil {
+					return err
+				}
+
+				if matched {
+					return TRUE
+				}
+			}
+
+			return FALSE
+		},
+	}
+
+	// callMethod() calls the function stored under a name in a hash with the hash prepended to the other arguments, so callMethod(obj, "name", x) is obj["name"](obj, x) and the function can use its first parameter as self
+	builtins["callMethod"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 2 {
+				return newError("wrong number of arguments. got=%d, want=at least 2", len(args))
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newError("first argument to 'callMethod' must be a HASH, got %s", args[0].Type())
+			}
+
+			name, ok := args[1].(*object.String)
+			if !ok {
+				return newError("second argument to 'callMethod' must be a STRING, got %s", args[1].Type())
+			}
+
+			pair, ok := hash.Pairs[name.HashKey()]
+			if !ok {
+				return newError("hash has no method: %s", name.Value)
+			}
+
+			if !isCallable(pair.Value) {
+				return newError("method '%s' must be a FUNCTION, got %s", name.Value, pair.Value.Type())
+			}
+
+			methodArgs := append([]object.Object{hash}, args[2:]...)
+
+			return applyFunction(pair.Value, methodArgs)
+		},
+	}
+
+	// sort() returns a new array with the elements of an array in ascending order. Numbers sort by value and strings lexicographically, an array mixing the two is an error.
+	// An optional comparator function sorts by its result instead, negative when its first argument goes first, positive when its second does and 0 to keep their order.
+	builtins["sort"] = &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("first argument to 'sort' must be an ARRAY, got %s", args[0].Type())
+			}
+
+			sorted := copyElements(arr.Elements)
+
+			if len(args) == 1 {
+				if err := checkSortable(sorted); err != nil {
+					return err
+				}
+
+				sort.SliceStable(sorted, func(i, j int) bool { return sortLess(sorted[i], sorted[j]) })
+
+				return &object.Array{Elements: sorted}
+			}
+
+			if !isCallable(args[1]) {
+				return newError("second argument to 'sort' must be a FUNCTION, got %s", args[1].Type())
+			}
+
+			// The first error stops the comparator being called again and is returned once sorting finishes
+			var err object.Object
+
+			sort.SliceStable(sorted, func(i, j int) bool {
+				if err != nil {
+					return false
+				}
+
+				result := applyFunction(args[1], []object.Object{sorted[i], sorted[j]})
+				if isError(result) {
+					err = result
+					return false
+				}
+
+				order, ok := result.(*object.Integer)
+				if !ok {
+					err = newError("comparator for 'sort' must return an INTEGER, got %s", typeOf(result))
+					return false
+				}
+
+				return order.Value < 0
+			})
+
+			if err != nil {
+				return err
+			}
+
+			return &object.Array{Elements: sorted}
+		},
+	}
+}
+
+// isCallable returns true for objects applyFunction can call
+func isCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.Builtin:
+		return true
+	default:
+		return false
+	}
+}
+
+// typeOf returns an object's type, or NULL's type for the nil result of a function whose body ends with a let statement
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL_OBJ
+	}
+
+	return obj.Type()
+}
+
+// stringKeyHash builds a hash from matching slices of string keys and values
+func stringKeyHash(keys []string, values []object.Object) *object.Hash {
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+
+	for i, key := range keys {
+		k := &object.String{Value: key}
+		hash.Set(k.HashKey(), object.HashPair{Key: k, Value: values[i]})
+	}
+
+	return hash
+}
+
+// normalizeIndex converts an index into a position from 0 to length for slicing. Negative indices count back from the end and indices out of range are clamped, so slice, substr, take, and drop all treat bounds the same way.
+// The index stays an int64 until it's clamped, where int is 32 bits a large index would otherwise wrap around to a small or negative one.
+func normalizeIndex(i int64, length int) int {
+	if i < 0 {
+		i += int64(length)
+	}
+
+	if i < 0 {
+		return 0
+	}
+
+	if i > int64(length) {
+		return length
+	}
+
+	return int(i)
+}
+
+// sliceBounds validates the integer start and optional end arguments of slice and substr and normalizes them for a value of the given length. An end before the start gives an empty range.
+func sliceBounds(name string, length int, args []object.Object) (int, int, *object.Error) {
+	start, ok := args[0].(*object.Integer)
+	if !ok {
+		return 0, 0, newError("second argument to '%s' must be an INTEGER, got %s", name, args[0].Type())
+	}
+
+	end := length
+
+	if len(args) == 2 {
+		e, ok := args[1].(*object.Integer)
+		if !ok {
+			return 0, 0, newError("third argument to '%s' must be an INTEGER, got %s", name, args[1].Type())
+		}
+
+		end = normalizeIndex(e.Value, length)
+	}
+
+	from := normalizeIndex(start.Value, length)
+
+	if end < from {
+		end = from
+	}
+
+	return from, end, nil
+}
+
+// arrayCount validates the array and integer count arguments of take and drop
+func arrayCount(name string, args ...object.Object) (*object.Array, int64, *object.Error) {
+	if len(args) != 2 {
+		return nil, 0, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, 0, newError("first argument to '%s' must be an ARRAY, got %s", name, args[0].Type())
+	}
+
+	n, ok := args[1].(*object.Integer)
+	if !ok {
+		return nil, 0, newError("second argument to '%s' must be an INTEGER, got %s", name, args[1].Type())
+	}
+
+	return arr, n.Value, nil
+}
+
+// copyElements copies a slice of array elements, so the new array doesn't share a backing array with the original
+func copyElements(elements []object.Object) []object.Object {
+	newElements := make([]object.Object, len(elements))
+	copy(newElements, elements)
+
+	return newElements
+}
+
+// deepCopy copies arrays and hashes, and everything nested in them. Other objects are never mutated, so they are shared rather than copied.
+func deepCopy(obj object.Object) object.Object {
+	switch obj := obj.(type) {
+	case *object.Array:
+		elements := make([]object.Object, len(obj.Elements))
+
+		for i, el := range obj.Elements {
+			elements[i] = deepCopy(el)
+		}
+
+		return &object.Array{Elements: elements}
+
+	case *object.Hash:
+		hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair, len(obj.Pairs))}
+
+		for _, pair := range obj.OrderedPairs() {
+			hash.Set(pair.Key.(object.Hashable).HashKey(), object.HashPair{Key: pair.Key, Value: deepCopy(pair.Value)})
+		}
+
+		return hash
+
+	default:
+		return obj
+	}
+}
+
+// checkArrayLength returns an error when an array of the given length would be larger than MaxArrayLength
+func checkArrayLength(length int) *object.Error {
+	if length > MaxArrayLength {
+		return newError("array length %d exceeds maximum size %d", length, MaxArrayLength)
+	}
+
+	return nil
+}
+
+// checkStringLength returns an error when a string of the given length in bytes would be longer than MaxStringLength
+func checkStringLength(length int) *object.Error {
+	if length > MaxStringLength {
+		return newError("string length exceeds maximum size %d", MaxStringLength)
+	}
+
+	return nil
+}
+
+// repeatedLength returns the length in bytes of a string of base bytes followed by count copies of a string of unit bytes, for checkStringLength. Compared by division, so a huge count can't overflow the multiplication; any length past MaxStringLength is returned as MaxStringLength + 1.
+func repeatedLength(base, unit int, count int64) int {
+	if unit > 0 && count > int64((MaxStringLength-base)/unit) {
+		return MaxStringLength + 1
+	}
+
+	return base + unit*int(count)
+}
+
+// predicateArguments validates the array and function arguments of find, every and some
+func predicateArguments(name string, args []object.Object) (*object.Array, *object.Error) {
+	if len(args) != 2 {
+		return nil, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, newError("first argument to '%s' must be an ARRAY, got %s", name, args[0].Type())
+	}
+
+	if !isCallable(args[1]) {
+		return nil, newError("second argument to '%s' must be a FUNCTION, got %s", name, args[1].Type())
+	}
+
+	return arr, nil
+}
+
+// checkSortable returns an error unless the elements are all numbers or all strings, the values sort can order without a comparator
+func checkSortable(elements []object.Object) *object.Error {
+	var first object.Object
+
+	for _, el := range elements {
+		switch el.(type) {
+		case *object.Integer, *object.Float, *object.String:
+		default:
+			return newError("can't sort elements of type %s without a comparator", typeOf(el))
+		}
+
+		if first == nil {
+			first = el
+		}
+
+		if (el.Type() == object.STRING_OBJ) != (first.Type() == object.STRING_OBJ) {
+			return newError("can't sort an array mixing %s and %s", first.Type(), el.Type())
+		}
+	}
+
+	return nil
+}
+
+// sortLess orders two numbers by value or two strings lexicographically. Integers compare exactly rather than as floats.
+func sortLess(a, b object.Object) bool {
+	switch a := a.(type) {
+	case *object.String:
+		return a.Value < b.(*object.String).Value
+	case *object.Integer:
+		if b, ok := b.(*object.Integer); ok {
+			return a.Value < b.Value
+		}
+	}
+
+	return toFloat(a) < toFloat(b)
+}
+
+// testPredicate calls a predicate function with an element and reports whether the result is truthy. A function ending in a let statement returns nothing, which counts as NULL.
+func testPredicate(fn, el object.Object) (bool, object.Object) {
+	result := applyFunction(fn, []object.Object{el})
+	if isError(result) {
+		return false, result
+	}
+
+	return result != nil && isTruthy(result), nil
 }
@@ -9,9 +9,13 @@ package evaluator
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/tmoore2016/interpreter/lib/ast"
+	"github.com/tmoore2016/interpreter/lib/lexer"
 	"github.com/tmoore2016/interpreter/lib/object"
+	"github.com/tmoore2016/interpreter/lib/parser"
 )
 
 // interpreter\evaluator\evaluator.go
@@ -21,8 +25,86 @@ var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+	ZERO  = &object.Integer{Value: 0} // returned for -0, integers are never mutated so it can be shared
 )
 
+// DivisionMode selects how / divides two integers
+type DivisionMode int
+
+// Division modes for IntegerDivision
+const (
+	TruncatingDivision DivisionMode = iota // 7 / 2 == 3, the default
+	FloatDivision                          // 7 / 2 == 3.5
+)
+
+// IntegerDivision is the division mode used for / between two integers. Embedders can change it before evaluating.
+var IntegerDivision = TruncatingDivision
+
+// TopLevelReturn allows a return statement outside of a function. When on, the default for files and -e, the returned value ends the program and becomes its result. An environment can override it for the programs evaluated in it with Environment.SetTopLevelReturn, as the REPL does, where a top-level return is an error.
+var TopLevelReturn = true
+
+// StrictConditions requires if conditions to be booleans. When off, the default, any value other than NULL or FALSE is truthy, so if (0) takes the true branch.
+var StrictConditions = false
+
+// EmptyFalsy makes empty strings, arrays and hashes falsy, so if ([]) takes the else branch and ![] is true. When off, the default, only NULL and FALSE are falsy.
+var EmptyFalsy = false
+
+// ContinueOnError keeps evaluating a program's top-level statements after one errors, for lint-like runs over a whole file. The program's result is then an error aggregating every top-level error in its Errors, or the one error if there's only one. When off, the default, the first error stops the program.
+var ContinueOnError = false
+
+// InternStrings makes string literals evaluate to shared interned strings (object.Intern) rather than a new object each time
+var InternStrings = false
+
+// EnforceTypeAnnotations makes let statements with a type annotation, let x: int = 5;, check the value's type. When off, the default, annotations are parsed but ignored.
+var EnforceTypeAnnotations = false
+
+// BareWords makes an identifier that isn't bound to anything evaluate to a string of its name, so config-style input like let team = Broncos; works without quotes. When off, the default, it's an "Identifier not found" error.
+var BareWords = false
+
+// MaxArrayLength is the most elements a builtin or operator will build an array with, and MaxStringLength the longest string in bytes.
+// They stop a script like strRepeat("x", 1000000000) from exhausting an embedding host's memory, and can be lowered or raised before evaluating.
+var (
+	MaxArrayLength  = 1 << 24
+	MaxStringLength = 1 << 28
+)
+
+// annotationTypes maps the type names used in let annotations to object types
+var annotationTypes = map[string]object.ObjectType{
+	"int":      object.INTEGER_OBJ,
+	"float":    object.FLOAT_OBJ,
+	"string":   object.STRING_OBJ,
+	"bool":     object.BOOLEAN_OBJ,
+	"array":    object.ARRAY_OBJ,
+	"hash":     object.HASH_OBJ,
+	"function": object.FUNCTION_OBJ,
+	"null":     object.NULL_OBJ,
+}
+
+// EvalAll parses and evaluates each input in order against a shared environment, like lines typed into the REPL, and returns each result.
+// An input with parser errors gets an error object listing them and the rest still run. Inputs that produce no value, like a let statement, give NULL.
+func EvalAll(inputs []string, env *object.Environment) []object.Object {
+	results := make([]object.Object, 0, len(inputs))
+
+	for _, input := range inputs {
+		p := parser.New(lexer.New(input))
+		program := p.ParseProgram()
+
+		if len(p.Errors()) != 0 {
+			results = append(results, newError("parser error(s): %s", strings.Join(p.Errors(), "; ")))
+			continue
+		}
+
+		result := Eval(program, env)
+		if result == nil {
+			result = NULL
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
 // Eval evaluates each AST node by sending the ast.Node interface as input to the object package
 func Eval(node ast.Node, env *object.Environment) object.Object {
 
@@ -45,18 +127,39 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 
+	// AST FloatLiteral node returns a Float object
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
 	// AST StringLiteral node returns a String Literal expression object with type and value
 	case *ast.StringLiteral:
+		if InternStrings {
+			return object.Intern(node.Value)
+		}
+
 		return &object.String{Value: node.Value}
 
 	// AST ArrayLiteral node returns an array literal expression object with element and index number
 	case *ast.ArrayLiteral:
+		// Checked before evaluating, so a literal that's too long has no side effects
+		if err := checkArrayLength(len(node.Elements)); err != nil {
+			return err
+		}
+
 		elements := evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
 
+	// AST ArrayComprehension node builds an array from each item of a collection
+	case *ast.ArrayComprehension:
+		return evalArrayComprehension(node, env)
+
+	// AST HashComprehension node builds a hash from each item of a collection
+	case *ast.HashComprehension:
+		return evalHashComprehension(node, env)
+
 	// AST IndexExpression node returns an array's index expression object from the running environment
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
@@ -69,12 +172,25 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(index) {
 			return index
 		}
+
+		if node.Default != nil {
+			return evalIndexWithDefault(left, index, node.Default, env)
+		}
+
 		return evalIndexExpression(left, index)
 
+	// AST slice expression returns a new array of the selected range
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
+
 	// AST Boolean node returns a Boolean expression object with type and value
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
+	// AST NullLiteral node returns the NULL var
+	case *ast.NullLiteral:
+		return NULL
+
 	// AST HashLiteral node evaluates HashLiterals
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
@@ -89,6 +205,11 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	// AST Infix expression evaluates the left and right node expressions, and then evaluates the operator
 	case *ast.InfixExpression:
+		// ?? only evaluates its right side when the left side is NULL
+		if node.Operator == "??" {
+			return evalCoalesceExpression(node, env)
+		}
+
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
@@ -97,12 +218,57 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(right) {
 			return right
 		}
+
+		if node.Operator == "|>" {
+			return evalPipeExpression(left, right)
+		}
+
 		return evalInfixExpression(node.Operator, left, right)
 
+	// AST sequence expression evaluates each expression in order and returns the last
+	case *ast.SequenceExpression:
+		var result object.Object
+
+		for _, e := range node.Expressions {
+			result = Eval(e, env)
+			if isError(result) {
+				return result
+			}
+		}
+
+		return result
+
+	// AST let-in expression binds its name in an enclosed environment, so it isn't visible after the body
+	case *ast.LetInExpression:
+		// The value is evaluated in the scope it's bound in, so a function literal captures it and can call itself by name
+		local := object.NewEnclosedEnvironment(env)
+
+		val := Eval(node.Value, local)
+		if isError(val) {
+			return val
+		}
+
+		if err := checkTypeAnnotation(node.Name, node.Type, val); err != nil {
+			return err
+		}
+
+		nameFunction(node.Name, node.Value, val)
+
+		local.Set(node.Name.Value, val)
+
+		return Eval(node.Body, local)
+
 	// AST if expression evaluates the If or If/Else expression node
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.TernaryExpression:
+		return evalTernaryExpression(node, env)
+
+	// AST while expression evaluates a while loop
+	case *ast.WhileExpression:
+		return evalWhileExpression(node, env)
+
 	// AST Return statement evaluates the return statement value and creates a Return Value object
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
@@ -119,9 +285,56 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return val
 		}
 
+		if err := checkTypeAnnotation(node.Name, node.Type, val); err != nil {
+			return err
+		}
+
+		if env.IsConst(node.Name.Value, false) {
+			return newError("cannot reassign constant: %s", node.Name.Value)
+		}
+
+		nameFunction(node.Name, node.Value, val)
+
 		// Let statements can set an environment association
 		env.Set(node.Name.Value, val)
 
+	// ConstStatement binds a name like a let statement, but the binding can't be reassigned or redeclared in the same scope
+	case *ast.ConstStatement:
+		val := Eval(node.Value, env)
+
+		if isError(val) {
+			return val
+		}
+
+		if env.IsConst(node.Name.Value, false) {
+			return newError("cannot reassign constant: %s", node.Name.Value)
+		}
+
+		nameFunction(node.Name, node.Value, val)
+
+		env.SetConst(node.Name.Value, val)
+
+	// AssignExpression updates an existing binding, or an element of an array or hash, and returns the new value
+	case *ast.AssignExpression:
+		if node.Index != nil {
+			return evalIndexAssignment(node.Index, node.Value, env)
+		}
+
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+
+		if env.IsConst(node.Name.Value, true) {
+			return newError("cannot reassign constant: %s", node.Name.Value)
+		}
+
+		if _, ok := env.Assign(node.Name.Value, val); !ok {
+			return newError("identifier not found: %s", node.Name.Value)
+		}
+
+		return val
+
 	// Identifier evaluates an AST identifier and returns the environment value
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
@@ -130,7 +343,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, Defaults: node.Defaults, Env: env, Body: body}
 
 	// CallExpression evaluates a list of expressions from a function as arguments, the process stops if there is an error.
 	case *ast.CallExpression:
@@ -139,12 +352,26 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return function
 		}
 
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
+		args, named, err := evalCallArguments(node.Arguments, env)
+		if err != nil {
+			return err
+		}
+
+		var result object.Object
+		var fromBody bool
+
+		if len(named) != 0 {
+			result, fromBody = applyFunctionWithNames(function, args, named)
+		} else {
+			result, fromBody = callFunction(function, args)
+		}
+
+		// An error binding the arguments happened at the call, not inside the function
+		if !fromBody {
+			return result
 		}
 
-		return applyFunction(function, args)
+		return addTraceFrame(result, node.Function, function)
 	}
 
 	return nil
@@ -155,26 +382,55 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 
 	var result object.Object
 
+	// Top-level errors so far, with ContinueOnError
+	var errs []*object.Error
+
 	// Evaluate all statements in the AST
 	for _, statement := range program.Statements {
 		result = Eval(statement, env)
 
+		// With top-level return off, as in the REPL, a return outside of a function is an error
+		if _, ok := result.(*object.ReturnValue); ok && !env.TopLevelReturn(TopLevelReturn) {
+			result = newError("return outside of a function")
+		}
+
 		switch result := result.(type) {
 
 		// If the last object evaluated was a ReturnValue, stop and return the unwrapped value
 		case *object.ReturnValue:
+			if len(errs) != 0 {
+				return aggregateErrors(errs)
+			}
+
 			return result.Value
 
-		// If the last object evaluated was an Error, stop and return the unwrapped value
+		// If the last object evaluated was an Error, stop and return the unwrapped value, or note it and go on with ContinueOnError
 		case *object.Error:
-			return result
+			if !ContinueOnError {
+				return result
+			}
+
+			errs = append(errs, result)
 		}
 	}
 
+	if len(errs) != 0 {
+		return aggregateErrors(errs)
+	}
+
 	// Return AST statements as objects
 	return result
 }
 
+// aggregateErrors returns the one error of a program, or an error collecting several in its Errors
+func aggregateErrors(errs []*object.Error) *object.Error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+
+	return &object.Error{Message: fmt.Sprintf("%d errors", len(errs)), Errors: errs}
+}
+
 // evalBlockStatement evaluates AST block statements such as the primary and alternative consequences of an If expression
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
@@ -198,8 +454,25 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
-// newError creates error objects and returns their value (message)
+// FriendlyTypeNames makes error messages use lowercase type names ("integer") instead of the ObjectType constants ("INTEGER")
+var FriendlyTypeNames = false
+
+// newError creates error objects and returns their value (message). When FriendlyTypeNames is on, any ObjectType argument is written with its friendly name.
 func newError(format string, a ...interface{}) *object.Error {
+	if FriendlyTypeNames {
+		friendly := make([]interface{}, len(a))
+
+		for i, arg := range a {
+			if t, ok := arg.(object.ObjectType); ok {
+				friendly[i] = object.FriendlyName(t)
+			} else {
+				friendly[i] = arg
+			}
+		}
+
+		a = friendly
+	}
+
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
@@ -240,28 +513,18 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	}
 }
 
-// evalNotOperatorExpression evaluates ! prefix expressions and returns the opposite.
+// evalNotOperatorExpression evaluates ! prefix expressions and returns the opposite of the value's truthiness, so ! and if always agree
 func evalNotOperatorExpression(right object.Object) object.Object {
-
-	switch right {
-
-	case TRUE:
-		return FALSE
-
-	case FALSE:
-		return TRUE
-
-	case NULL:
-		return TRUE
-
-	default:
-		return FALSE
-	}
+	return nativeBoolToBooleanObject(!isTruthy(right))
 }
 
 // evalMinusPrefixOperatorExpression evaluates - prefix operators and if the right side of the prefix expression is an integer, returns the negative value.
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 
+	if f, ok := right.(*object.Float); ok {
+		return &object.Float{Value: -f.Value}
+	}
+
 	// Return error if the right side expression isn't an integer
 	if right.Type() != object.INTEGER_OBJ {
 		return newError("Illegal prefix operation, expected integer, received: -%s", right.Type())
@@ -269,6 +532,11 @@ func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 
 	value := right.(*object.Integer).Value
 
+	// -0 is 0 for integers, reuse ZERO rather than allocating
+	if value == 0 {
+		return ZERO
+	}
+
 	// Apply the negative value to an integer
 	return &object.Integer{Value: -value}
 }
@@ -285,17 +553,33 @@ func evalInfixExpression(
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
 
+	// When either side is a float and the other is a number, the integer is promoted and a float infix expression is evaluated
+	case isNumber(left) && isNumber(right) && (left.Type() == object.FLOAT_OBJ || right.Type() == object.FLOAT_OBJ):
+		return evalFloatInfixExpression(operator, toFloat(left), toFloat(right))
+
+	// A string % a value or an array of values formats the string, before any other string or null rule so "%s" % "x" and "%s" % null format too
+	case operator == "%" && left.Type() == object.STRING_OBJ:
+		return formatString(left.(*object.String).Value, right)
+
 	// When left and right sides are strings, evaluate a string infix expression
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 
-	// If infix operator is ==, it will make a pointer comparison between left and right booleans. This works because there are only two Boolean expressions, the vars TRUE and FALSE and they are always in the same memory address. It won't work for integers, but those are compared in the switch statement above.
+	// null is only equal to null, even though it's falsy like false, and every other operator errors rather than treating it as 0 or ""
+	case left.Type() == object.NULL_OBJ || right.Type() == object.NULL_OBJ:
+		return evalNullInfixExpression(operator, left, right)
+
+	// Any other == compares by value with object.Equals, so arrays and hashes are equal when their contents are. Functions are compared by identity, so a function is only equal to itself.
 	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
+		return nativeBoolToBooleanObject(object.Equals(left, right))
 
 	// Works the same as ==
 	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
+		return nativeBoolToBooleanObject(!object.Equals(left, right))
+
+	// Functions are only equal to themselves and have no order
+	case isOrdering(operator) && (isCallable(left) || isCallable(right)):
+		return newError("ordering comparison not supported for functions: %s %s %s", left.Type(), operator, right.Type())
 
 	// Create new error object if unrelated types are compared
 	case left.Type() != right.Type():
@@ -307,6 +591,48 @@ func evalInfixExpression(
 	}
 }
 
+// evalNullInfixExpression evaluates an infix expression with null on at least one side. Only == and != are defined, comparing by type since every null is equal.
+func evalNullInfixExpression(operator string, left, right object.Object) object.Object {
+	bothNull := left.Type() == right.Type()
+
+	switch operator {
+	case "==":
+		return nativeBoolToBooleanObject(bothNull)
+	case "!=":
+		return nativeBoolToBooleanObject(!bothNull)
+	default:
+		return newError("operation on null: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// isOrdering returns true for the comparison operators that order values
+func isOrdering(operator string) bool {
+	return operator == "<" || operator == ">" || operator == "<=" || operator == ">="
+}
+
+// evalCoalesceExpression evaluates a ?? infix expression. The left value is returned unless it is NULL, in which case the right side is evaluated and returned. Unlike a truthiness check, false and 0 are kept.
+func evalCoalesceExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	if left != NULL {
+		return left
+	}
+
+	return Eval(node.Right, env)
+}
+
+// evalPipeExpression calls the function on the right of |> with the value on the left, x |> f is f(x)
+func evalPipeExpression(left, right object.Object) object.Object {
+	if !isCallable(right) {
+		return newError("right side of '|>' must be a FUNCTION, got %s", right.Type())
+	}
+
+	return applyFunction(right, []object.Object{left})
+}
+
 // evalIntegerInfixExpression evaluates the operator of an infix expression.
 func evalIntegerInfixExpression(
 	operator string,
@@ -327,14 +653,35 @@ func evalIntegerInfixExpression(
 		return &object.Integer{Value: leftVal * rightVal}
 
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero")
+		}
+
+		if IntegerDivision == FloatDivision {
+			return &object.Float{Value: float64(leftVal) / float64(rightVal)}
+		}
+
 		return &object.Integer{Value: leftVal / rightVal}
 
+	case "%":
+		if rightVal == 0 {
+			return newError("modulo by zero")
+		}
+
+		return &object.Integer{Value: leftVal % rightVal}
+
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
 
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 
@@ -343,23 +690,179 @@ func evalIntegerInfixExpression(
 
 	// Return new error object if unsupported operator is used
 	default:
-		return newError("Invalid Infix Expression operator, expected ('+' , '-', '*', '/', '<', '>', '==', '!='),/n received: %s %s %s", left.Type(), operator, right.Type())
+		return newError("Invalid Infix Expression operator, expected ('+' , '-', '*', '/', '%', '<', '>', '<=', '>=', '==', '!='),/n received: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalFloatInfixExpression evaluates the operator of an infix expression with float operands
+func evalFloatInfixExpression(operator string, leftVal, rightVal float64) object.Object {
+	switch operator {
+
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+
+	default:
+		return newError("Invalid operator: %s %s %s", object.FLOAT_OBJ, operator, object.FLOAT_OBJ)
+	}
+}
+
+// isNumber returns true for integers and floats
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.FLOAT_OBJ
+}
+
+// toFloat returns the value of an integer or float as a float64
+func toFloat(obj object.Object) float64 {
+	if i, ok := obj.(*object.Integer); ok {
+		return float64(i.Value)
 	}
+
+	return obj.(*object.Float).Value
 }
 
-// evalStringInfixExpression evaluates string operations. Currently only concatenation.
-// To add == and != String comparisons, put here and use values rather than pointers.
+// evalStringInfixExpression evaluates string operations: concatenation with +, and == and != comparing the string values rather than pointers.
+// Each + copies both strings into a new one, so building a string with + in a loop is O(n²), concatStrings() is the fast alternative.
 func evalStringInfixExpression(
 	operator string,
 	left, right object.Object,
 ) object.Object {
-	if operator != "+" {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+
+	switch operator {
+	case "+":
+		if err := checkStringLength(len(leftVal) + len(rightVal)); err != nil {
+			return err
+		}
+		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
 		return newError("Invalid operator: %s %s %s", left.Type(), operator, right.Type())
 	}
+}
+
+// formatString fills the verbs of a format string with values, like Python's "%d items" % count. An array supplies one value per verb, so a single array value has to be wrapped, "%s" % [[1, 2]].
+// Verbs are %d for an integer, %f or %.Nf for a number with six or N decimals, %s for any value as str() would write it, and %% for a literal %. Too few or too many values, a value of the wrong type or any other verb is an error.
+func formatString(format string, arg object.Object) object.Object {
+	values := []object.Object{arg}
+	if arr, ok := arg.(*object.Array); ok {
+		values = arr.Elements
+	}
 
-	leftVal := left.(*object.String).Value
-	rightVal := right.(*object.String).Value
-	return &object.String{Value: leftVal + rightVal}
+	var out strings.Builder
+	used := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+
+		// Optional precision for %f, %.2f, six digits like printf when there isn't one
+		precision := 6
+		j := i + 1
+		if j < len(format) && format[j] == '.' {
+			k := j + 1
+			for k < len(format) && format[k] >= '0' && format[k] <= '9' {
+				k++
+			}
+			precision, _ = strconv.Atoi(format[j+1 : k]) // %.f is 0 digits
+			j = k
+		}
+
+		if j >= len(format) {
+			return newError("format string ends with an incomplete verb: %s", format[i:])
+		}
+
+		verb := format[j]
+		start := i
+		spec := format[start : j+1] // the whole verb, %d or %.2f
+		i = j
+
+		if spec == "%%" {
+			out.WriteByte('%')
+			continue
+		}
+
+		if spec != "%d" && spec != "%s" && verb != 'f' {
+			// Name the whole verb as written, flags and width included, %5d rather than %5
+			end := j
+			for end < len(format) && strings.IndexByte("0123456789.-+#", format[end]) >= 0 {
+				end++
+			}
+			if end < len(format) {
+				end++
+			}
+
+			return newError("unsupported format verb: %s", format[start:end])
+		}
+
+		if used == len(values) {
+			return newError("not enough values for format string. got=%d", len(values))
+		}
+
+		value := values[used]
+		used++
+
+		switch {
+		case spec == "%d" && typeOf(value) == object.INTEGER_OBJ:
+			out.WriteString(strconv.FormatInt(value.(*object.Integer).Value, 10))
+		case verb == 'f' && value != nil && isNumber(value):
+			out.WriteString(strconv.FormatFloat(toFloat(value), 'f', precision, 64))
+		case spec == "%s":
+			if str, ok := value.(*object.String); ok {
+				out.WriteString(str.Value)
+			} else if value != nil {
+				out.WriteString(value.Inspect())
+			} else {
+				out.WriteString(NULL.Inspect())
+			}
+		case spec == "%d":
+			return newError("format verb %%d expects an INTEGER, got %s", typeOf(value))
+		default:
+			return newError("format verb %s expects a number, got %s", spec, typeOf(value))
+		}
+
+		if err := checkStringLength(out.Len()); err != nil {
+			return err
+		}
+	}
+
+	if used != len(values) {
+		return newError("too many values for format string. got=%d, want=%d", len(values), used)
+	}
+
+	return &object.String{Value: out.String()}
 }
 
 // evalIfExpression evaluates the conditions of an If or If/Else expression
@@ -370,13 +873,18 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 		return condition
 	}
 
+	if err := strictConditionError(condition); err != nil {
+		return err
+	}
+
+	// Each branch is its own scope, a let inside it isn't visible after the if
 	// Condition is truthy, not null or false, return primary consequence
 	if isTruthy(condition) {
-		return Eval(ie.Consequence, env)
+		return Eval(ie.Consequence, object.NewEnclosedEnvironment(env))
 
 		// If alternative consequence (else) applies, return that instead
 	} else if ie.Alternative != nil {
-		return Eval(ie.Alternative, env)
+		return Eval(ie.Alternative, object.NewEnclosedEnvironment(env))
 
 		// If neither primary or alternative consequence applies, return NULL
 	} else {
@@ -384,23 +892,219 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 }
 
-// isTruthy defines what truthy is: not NULL or FALSE
-func isTruthy(obj object.Object) bool {
+// evalTernaryExpression evaluates the condition of cond ? a : b, then only the branch it chooses
+func evalTernaryExpression(te *ast.TernaryExpression, env *object.Environment) object.Object {
+	condition := Eval(te.Condition, env)
+	if isError(condition) {
+		return condition
+	}
 
-	switch obj {
+	if err := strictConditionError(condition); err != nil {
+		return err
+	}
 
-	case NULL:
-		return false
+	if isTruthy(condition) {
+		return Eval(te.Consequence, env)
+	}
 
-	case TRUE:
-		return true
+	return Eval(te.Alternative, env)
+}
 
-	case FALSE:
+// checkTypeAnnotation returns an error when EnforceTypeAnnotations is on and the value doesn't match the let statement's type annotation, otherwise nil
+func checkTypeAnnotation(name, annotation *ast.Identifier, val object.Object) *object.Error {
+	if !EnforceTypeAnnotations || annotation == nil {
+		return nil
+	}
+
+	expected, ok := annotationTypes[annotation.Value]
+	if !ok {
+		return newError("unknown type annotation: %s", annotation.Value)
+	}
+
+	// Builtins are functions too
+	if expected == object.FUNCTION_OBJ && isCallable(val) {
+		return nil
+	}
+
+	if typeOf(val) != expected {
+		return newError("type annotation mismatch: %s is %s, got %s", name.Value, annotation.Value, typeOf(val))
+	}
+
+	return nil
+}
+
+// evalWhileExpression evaluates the body until the condition is falsy. The result is the value of the last pass through the body, or NULL if it never ran. A return or error in the body ends the loop and is passed up.
+func evalWhileExpression(we *ast.WhileExpression, env *object.Environment) object.Object {
+	var result object.Object = NULL
+
+	for {
+		condition := Eval(we.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if err := strictConditionError(condition); err != nil {
+			return err
+		}
+
+		if !isTruthy(condition) {
+			return result
+		}
+
+		// Each pass through the body is its own scope, so a let inside it starts fresh and isn't visible after the loop
+		result = Eval(we.Body, object.NewEnclosedEnvironment(env))
+
+		// A body ending in a let statement has no value
+		if result == nil {
+			result = NULL
+			continue
+		}
+
+		if rt := result.Type(); rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			return result
+		}
+	}
+}
+
+// evalArrayComprehension evaluates the element expression for each item of the collection the condition allows, and returns the results as an array
+func evalArrayComprehension(node *ast.ArrayComprehension, env *object.Environment) object.Object {
+	elements := []object.Object{}
+
+	err := evalComprehensionClause(node.Variable, node.Collection, node.Condition, env, func(local *object.Environment) object.Object {
+		// An error stops the comprehension, so the limit is checked before each element is added
+		if err := checkArrayLength(len(elements) + 1); err != nil {
+			return err
+		}
+
+		element := Eval(node.Element, local)
+		if !isError(element) {
+			elements = append(elements, element)
+		}
+		return element
+	})
+	if err != nil {
+		return err
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// evalHashComprehension evaluates the key and value expressions for each item of the collection the condition allows, and returns the pairs as a hash. A later pair with the same key replaces an earlier one.
+func evalHashComprehension(node *ast.HashComprehension, env *object.Environment) object.Object {
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
+
+	err := evalComprehensionClause(node.Variable, node.Collection, node.Condition, env, func(local *object.Environment) object.Object {
+		key := Eval(node.Key, local)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("Unusable as hash key: %s", key.Type())
+		}
+
+		value := Eval(node.Value, local)
+		if isError(value) {
+			return value
+		}
+
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
+
+		return value
+	})
+	if err != nil {
+		return err
+	}
+
+	return hash
+}
+
+// evalComprehensionClause evaluates a comprehension's collection and calls each with an enclosed environment binding the variable to one item, skipping items the condition is falsy for.
+// Arrays give their elements and hashes their keys, in insertion order. An error from the collection, the condition or each ends the loop and is returned, otherwise the result is nil.
+func evalComprehensionClause(variable *ast.Identifier, collection, condition ast.Expression, env *object.Environment, each func(local *object.Environment) object.Object) object.Object {
+	items := Eval(collection, env)
+	if isError(items) {
+		return items
+	}
+
+	var elements []object.Object
+
+	switch items := items.(type) {
+	case *object.Array:
+		elements = items.Elements
+	case *object.Hash:
+		for _, pair := range items.OrderedPairs() {
+			elements = append(elements, pair.Key)
+		}
+	default:
+		return newError("comprehension collection must be an ARRAY or HASH, got %s", typeOf(items))
+	}
+
+	for _, item := range elements {
+		local := object.NewEnclosedEnvironment(env)
+		local.Set(variable.Value, item)
+
+		if condition != nil {
+			allowed := Eval(condition, local)
+			if isError(allowed) {
+				return allowed
+			}
+
+			if err := strictConditionError(allowed); err != nil {
+				return err
+			}
+
+			if !isTruthy(allowed) {
+				continue
+			}
+		}
+
+		if result := each(local); isError(result) {
+			return result
+		}
+	}
+
+	return nil
+}
+
+// strictConditionError returns an error for a non-boolean condition when StrictConditions is on, otherwise nil
+func strictConditionError(condition object.Object) *object.Error {
+	if !StrictConditions || typeOf(condition) == object.BOOLEAN_OBJ {
+		return nil
+	}
+
+	return newError("condition must be boolean, got %s", typeOf(condition))
+}
+
+// isTruthy defines what truthy is: not NULL or FALSE, and with EmptyFalsy not an empty string, array or hash
+func isTruthy(obj object.Object) bool {
+
+	switch obj {
+
+	case NULL:
 		return false
 
-	default: // This isn't working as I'd like. If something isn't NULL or FALSE it should be true, but an identifier assigned a value isn't true or false in Doorkey because its never checked as a Boolean.
+	case TRUE:
 		return true
+
+	case FALSE:
+		return false
 	}
+
+	if EmptyFalsy {
+		switch obj := obj.(type) {
+		case *object.String:
+			return obj.Value != ""
+		case *object.Array:
+			return len(obj.Elements) != 0
+		case *object.Hash:
+			return len(obj.Pairs) != 0
+		}
+	}
+
+	// If something isn't NULL or FALSE it's true, an identifier assigned a value isn't true or false in Doorkey because it's never checked as a Boolean
+	return true
 }
 
 // evalHashLiteral evaluates the key node to determine it is a hashable type, then evaluates the value node and adds the key-value pair to the pairs map by calling HashKey(). A new HashPair object is created by pointing to key and value and added to pairs.
@@ -408,9 +1112,9 @@ func evalHashLiteral(
 	node *ast.HashLiteral,
 	env *object.Environment,
 ) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
 
-	for keyNode, valueNode := range node.Pairs {
+	for _, keyNode := range node.Keys() {
 		key := Eval(keyNode, env)
 
 		if isError(key) {
@@ -423,7 +1127,7 @@ func evalHashLiteral(
 			return newError("Unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(node.Pairs[keyNode], env)
 
 		if isError(value) {
 			return value
@@ -431,10 +1135,10 @@ func evalHashLiteral(
 
 		hashed := hashKey.HashKey()
 
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashed, object.HashPair{Key: key, Value: value})
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 // evalIdentifier evaluates an AST identifier node and retrieves its value from the environment association, if it exists.
@@ -448,12 +1152,16 @@ func evalIdentifier(
 	}
 
 	// Fallback when identifier is not bound to value in current environment, checks builtin functions (builtins.go)
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin, ok := lookupBuiltin(node.Value, env); ok {
 		return builtin
 	}
 
+	if BareWords {
+		return &object.String{Value: node.Value}
+	}
+
 	// Failure mode
-	return newError("Identifier not found: " + node.Value)
+	return newError("Identifier not found: %s", node.Value)
 }
 
 // evalExpressions evaluates ast.Expressions from a function in the context of the current environment
@@ -476,6 +1184,56 @@ func evalExpressions(
 	return result
 }
 
+// evalSliceExpression evaluates arr[low:high]. A missing low is the start and a missing high is the end. Bounds are normalized like the slice builtin: negative bounds count from the end and out of range bounds are clamped.
+func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	arr, ok := left.(*object.Array)
+	if !ok {
+		return newError("slice operator not supported: %s", left.Type())
+	}
+
+	length := len(arr.Elements)
+
+	low, err := evalSliceBound(node.Low, 0, length, env)
+	if err != nil {
+		return err
+	}
+
+	high, err := evalSliceBound(node.High, length, length, env)
+	if err != nil {
+		return err
+	}
+
+	if high < low {
+		high = low
+	}
+
+	return &object.Array{Elements: copyElements(arr.Elements[low:high])}
+}
+
+// evalSliceBound evaluates one bound of a slice and normalizes it, or returns the fallback when the bound was left out
+func evalSliceBound(exp ast.Expression, fallback, length int, env *object.Environment) (int, object.Object) {
+	if exp == nil {
+		return fallback, nil
+	}
+
+	evaluated := Eval(exp, env)
+	if isError(evaluated) {
+		return 0, evaluated
+	}
+
+	i, ok := evaluated.(*object.Integer)
+	if !ok {
+		return 0, newError("slice bounds must be INTEGER, got %s", evaluated.Type())
+	}
+
+	return normalizeIndex(i.Value, length), nil
+}
+
 // evalIndexExpression accepts an array object and the array's index, if both are valid it calls evalArrayIndexExpression
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
@@ -483,6 +1241,12 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
 
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		if char, ok := stringIndex(left.(*object.String).Value, index.(*object.Integer).Value); ok {
+			return char
+		}
+		return NULL
+
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
 
@@ -491,6 +1255,56 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	}
 }
 
+// evalIndexAssignment sets an element of an array or hash in place, arr[0] = 5 or hash["key"] = 5, and returns the value. The array or hash and the index are evaluated before the value. An array index must already exist, a hash key is added if it's missing.
+func evalIndexAssignment(target *ast.IndexExpression, valueNode ast.Expression, env *object.Environment) object.Object {
+	left := Eval(target.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	index := Eval(target.Index, env)
+	if isError(index) {
+		return index
+	}
+
+	val := Eval(valueNode, env)
+	if isError(val) {
+		return val
+	}
+
+	// A function whose body ends with a let statement returns nil, store it as null
+	if val == nil {
+		val = NULL
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newError("array index must be an INTEGER, got %s", typeOf(index))
+		}
+
+		if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+			return newError("array index out of range: %d", idx.Value)
+		}
+
+		left.Elements[idx.Value] = val
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("Unusable as hash key: %s", typeOf(index))
+		}
+
+		left.Set(key.HashKey(), object.HashPair{Key: index, Value: val})
+
+	default:
+		return newError("Index assignment not supported: %s", typeOf(left))
+	}
+
+	return val
+}
+
 // evalArrayIndexExpression matches an element of an array with its index, and returns an object containing the element and index number
 func evalArrayIndexExpression(array, index object.Object) object.Object {
 	arrayObject := array.(*object.Array)
@@ -504,6 +1318,23 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
+// stringIndex returns the character at index i of a string as a one-character string, counting characters rather than bytes. It returns false when i is out of range.
+func stringIndex(str string, i int64) (*object.String, bool) {
+	if i < 0 {
+		return nil, false
+	}
+
+	var n int64
+	for _, char := range str {
+		if n == i {
+			return &object.String{Value: string(char)}, true
+		}
+		n++
+	}
+
+	return nil, false
+}
+
 // evalHashIndexExpression matches a hash key to its value, if the hash key doesn't exist, returns null
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	hashObject := hash.(*object.Hash)
@@ -523,38 +1354,271 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 	return pair.Value
 }
 
+// evalIndexWithDefault evaluates hash["key", default] and array[i, default]. The default expression is only evaluated when the key or index is missing, a key bound to null is still returned.
+func evalIndexWithDefault(
+	left, index object.Object,
+	defaultNode ast.Expression,
+	env *object.Environment,
+) object.Object {
+	switch left := left.(type) {
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+
+		if !ok {
+			return newError("Unusable as hash key: %s", index.Type())
+		}
+
+		if pair, ok := left.Pairs[key.HashKey()]; ok {
+			return pair.Value
+		}
+
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+
+		if !ok {
+			return newError("Index operator not supported: %s", left.Type())
+		}
+
+		if idx.Value >= 0 && idx.Value < int64(len(left.Elements)) {
+			return left.Elements[idx.Value]
+		}
+
+	case *object.String:
+		idx, ok := index.(*object.Integer)
+
+		if !ok {
+			return newError("Index operator not supported: %s", left.Type())
+		}
+
+		if char, ok := stringIndex(left.Value, idx.Value); ok {
+			return char
+		}
+
+	default:
+		return newError("Index operator not supported: %s", left.Type())
+	}
+
+	return Eval(defaultNode, env)
+}
+
 // applyFunction verifies a function object and converts the function parameter to *object.Function to access the .Env and .Body fields.
 func applyFunction(fn object.Object, args []object.Object) object.Object {
+	result, _ := callFunction(fn, args)
+	return result
+}
+
+// callFunction is applyFunction, also reporting whether the result came from evaluating the function's body. It's false for an error binding the arguments, a partial application or a builtin.
+func callFunction(fn object.Object, args []object.Object) (object.Object, bool) {
 	switch fn := fn.(type) {
 
 	// Standard object.Function types
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		// Too few arguments returns a partially applied function waiting for the rest
+		if len(args) < requiredParameters(fn) {
+			return partialFunction(fn, args), false
+		}
+
+		if len(args) > len(fn.Parameters) {
+			return newError("wrong number of arguments. got=%d, want=%d", len(args), len(fn.Parameters)), false
+		}
+
+		extendedEnv, err := extendFunctionEnv(fn, args, nil)
+		if err != nil {
+			return err, false
+		}
+
 		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+		return unwrapReturnValue(evaluated), true
 
 	// Builtin function types
 	case *object.Builtin:
-		return fn.Fn(args...)
+		return fn.Fn(args...), false
 
 	default:
-		return newError("Not a function, received type: %s", fn.Type())
+		return newError("Not a function, received type: %s", fn.Type()), false
+	}
+}
+
+// nameFunction gives a function the name it's bound to by a let, when the value is a function literal. Functions bound from another name keep the name they were defined with.
+func nameFunction(name *ast.Identifier, value ast.Expression, val object.Object) {
+	if _, ok := value.(*ast.FunctionLiteral); !ok {
+		return
+	}
+
+	if fn, ok := val.(*object.Function); ok {
+		fn.Name = name.Value
+	}
+}
+
+// addTraceFrame records the called function in the trace of an error that propagated out of its body, so errors from nested calls show the calling chain. Only calls to Doorkey functions are recorded.
+func addTraceFrame(result object.Object, callee ast.Expression, function object.Object) object.Object {
+	err, ok := result.(*object.Error)
+	if !ok {
+		return result
+	}
+
+	if _, ok := function.(*object.Function); !ok {
+		return result
+	}
+
+	// A let-bound function is recorded by its own name, otherwise by the expression it was called through
+	name := function.(*object.Function).Name
+
+	if _, ok := callee.(*ast.FunctionLiteral); ok && name == "" {
+		name = "anonymous function"
+	} else if name == "" {
+		name = callee.String()
+	}
+
+	err.Trace = append(err.Trace, name)
+
+	return err
+}
+
+// applyFunctionWithNames calls a function with positional arguments followed by named arguments. Each name must be a parameter that doesn't already have a positional argument, and every parameter without a default must get a value. Unlike a positional call, too few arguments is an error rather than a partial application. Like callFunction, it also reports whether the result came from evaluating the function's body.
+func applyFunctionWithNames(fn object.Object, args []object.Object, named map[string]object.Object) (object.Object, bool) {
+	function, ok := fn.(*object.Function)
+	if !ok {
+		if _, ok := fn.(*object.Builtin); ok {
+			return newError("named arguments are not supported for builtin functions"), false
+		}
+
+		return newError("Not a function, received type: %s", typeOf(fn)), false
+	}
+
+	if len(args) > len(function.Parameters) {
+		return newError("wrong number of arguments. got=%d, want=%d", len(args), len(function.Parameters)), false
+	}
+
+	if err := checkNamedArguments(function, len(args), named); err != nil {
+		return err, false
+	}
+
+	extendedEnv, err := extendFunctionEnv(function, args, named)
+	if err != nil {
+		return err, false
 	}
+
+	evaluated := Eval(function.Body, extendedEnv)
+	return unwrapReturnValue(evaluated), true
+}
+
+// checkNamedArguments returns an error if a named argument isn't a parameter or repeats a positional argument, or if a parameter without a default has no argument
+func checkNamedArguments(fn *object.Function, positional int, named map[string]object.Object) *object.Error {
+	for name := range named {
+		index := -1
+
+		for i, param := range fn.Parameters {
+			if param.Value == name {
+				index = i
+				break
+			}
+		}
+
+		if index == -1 {
+			return newError("unknown parameter name: %s", name)
+		}
+
+		if index < positional {
+			return newError("argument %s given more than once", name)
+		}
+	}
+
+	for _, param := range fn.Parameters[positional:] {
+		_, isNamed := named[param.Value]
+		_, hasDefault := fn.Defaults[param.Value]
+
+		if !isNamed && !hasDefault {
+			return newError("missing argument for parameter %s", param.Value)
+		}
+	}
+
+	return nil
+}
+
+// evalCallArguments evaluates a call's arguments in order, splitting them into positional arguments and named arguments by name. An error stops evaluation and is returned on its own.
+func evalCallArguments(exps []ast.Expression, env *object.Environment) ([]object.Object, map[string]object.Object, object.Object) {
+	var args []object.Object
+	var named map[string]object.Object
+
+	for _, e := range exps {
+		arg, isNamed := e.(*ast.NamedArgument)
+		if !isNamed {
+			evaluated := Eval(e, env)
+			if isError(evaluated) {
+				return nil, nil, evaluated
+			}
+
+			args = append(args, evaluated)
+			continue
+		}
+
+		if _, ok := named[arg.Name.Value]; ok {
+			return nil, nil, newError("argument %s given more than once", arg.Name.Value)
+		}
+
+		evaluated := Eval(arg.Value, env)
+		if isError(evaluated) {
+			return nil, nil, evaluated
+		}
+
+		if named == nil {
+			named = make(map[string]object.Object)
+		}
+
+		named[arg.Name.Value] = evaluated
+	}
+
+	return args, named, nil
+}
+
+// partialFunction binds the given arguments to the function's first parameters and returns a new function taking the remaining parameters, add(1) is fn(b) { 1 + b }
+func partialFunction(fn *object.Function, args []object.Object) *object.Function {
+	env := object.NewEnclosedEnvironment(fn.Env)
+
+	for paramIdx, arg := range args {
+		env.Set(fn.Parameters[paramIdx].Value, arg)
+	}
+
+	return &object.Function{Parameters: fn.Parameters[len(args):], Defaults: fn.Defaults, Body: fn.Body, Env: env}
+}
+
+// requiredParameters returns the number of parameters without a default value. They always come first.
+func requiredParameters(fn *object.Function) int {
+	return len(fn.Parameters) - len(fn.Defaults)
 }
 
 // extendFunctionEnv creates a new *object.Environment that's enclosed by the function's environment. This allows the function's arguments to bind to the function's parameter names without overwriting the original environment.
+// Parameters after the positional arguments are bound from the named arguments, and any left get their default values, evaluated at call time in the new environment so they can use earlier parameters. An error from a default is returned with a nil environment.
 func extendFunctionEnv(
 	fn *object.Function,
 	args []object.Object,
-) *object.Environment {
+	named map[string]object.Object,
+) (*object.Environment, object.Object) {
 
 	env := object.NewEnclosedEnvironment(fn.Env)
 
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		if paramIdx < len(args) {
+			env.Set(param.Value, args[paramIdx])
+			continue
+		}
+
+		if val, ok := named[param.Value]; ok {
+			env.Set(param.Value, val)
+			continue
+		}
+
+		val := Eval(fn.Defaults[param.Value], env)
+		if isError(val) {
+			return nil, val
+		}
+
+		env.Set(param.Value, val)
 	}
 
-	return env
+	return env, nil
 }
 
 // unwrapReturnValue unwraps the outer environment for *object.ReturnValues so that evalBlockStatement will evaluate the entire block statement and not just the outer function.
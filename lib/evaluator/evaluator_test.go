@@ -8,6 +8,11 @@ By following "Writing an Interpreter in Go" by Thorsten Ball, https://interprete
 package evaluator
 
 import (
+	"bytes"
+	"math"
+	"os"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/tmoore2016/interpreter/lib/lexer"
@@ -17,11 +22,15 @@ import (
 
 // testEval sends input to the lexer, parses it, assigns it to an AST program node, and returns the evaluated node.
 func testEval(input string) object.Object {
+	return testEvalIn(input, object.NewEnvironment())
+}
+
+// testEvalIn evaluates input in env, for tests that keep state such as input and output between evaluations
+func testEvalIn(input string, env *object.Environment) object.Object {
 
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
-	env := object.NewEnvironment()
 
 	return Eval(program, env)
 }
@@ -46,6 +55,10 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"(8 - 6) / 2 - 1", 0},
 		{"-(2 + 2) - 10", -14},
 		{"(6 + 5 - 2 + 1) * 4 / 8 + -9", -4},
+		{"10 % 3", 1},
+		{"9 % 3", 0},
+		{"-7 % 3", -1},
+		{"2 + 10 % 4 * 3", 8},
 	}
 
 	// For each test input, send to testEval() and confirm that the evaluated output is equal to expected output
@@ -75,6 +88,23 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+// testFloatObject fails if the object isn't a Float with the expected value
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+
+	if !ok {
+		t.Errorf("Object is not a Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("Object has the wrong value. got=%g, want=%g", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
 // TestStringObject fails if the expected object type and value aren't the actual type or value.
 func TestStringObject(t *testing.T) {
 	input := `"Doorkey has strings!"`
@@ -123,6 +153,21 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"10 > 5", true},
 		{"1 < 1", false},
 		{"1 > 1", false},
+		{"5 <= 5", true},
+		{"5 >= 5", true},
+		{"4 <= 5", true},
+		{"4 >= 5", false},
+		{"6 <= 5", false},
+		{"6 >= 5", true},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+		{`"a" != "b"`, true},
+		{`"Peanut" + "Butter" == "PeanutButter"`, true},
+		{`let s = "x"; s == s`, true},
+		{`"" == ""`, true},
+		{"1.5 <= 1.5", true},
+		{"2 >= 2.5", false},
 		{"4 == 4", true},
 		{"4 != 4", false},
 		{"4 == 5", false},
@@ -191,6 +236,61 @@ func TestNotOperator(t *testing.T) {
 	}
 }
 
+// TestNotKeyword tests that the 'not' keyword evaluates identically to the ! prefix operator
+func TestNotKeyword(t *testing.T) {
+	tests := []struct {
+		wordInput   string
+		symbolInput string
+	}{
+		{"not true", "!true"},
+		{"not false", "!false"},
+		{"not 5", "!5"},
+		{"not not true", "!!true"},
+		{"let done = false; not done", "let done = false; !done"},
+	}
+
+	for _, tt := range tests {
+		word := testEval(tt.wordInput)
+		symbol := testEval(tt.symbolInput)
+
+		if word != symbol {
+			t.Errorf("%q evaluated to %s, want %s", tt.wordInput, word.Inspect(), symbol.Inspect())
+		}
+	}
+}
+
+// TestNullCoalescing tests that ?? returns the left value unless it is NULL, and only evaluates the right side when needed
+func TestNullCoalescing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"null ?? 5", 5},
+		{"3 ?? 5", 3},
+		{"0 ?? 5", 0},
+		{"false ?? true", false},
+		{"null ?? null", nil},
+		{"if (false) { 1 } ?? 2", 2},
+		{"null ?? null ?? 7", 7},
+		// The right side would be an error if it were evaluated
+		{"3 ?? undefinedName", 3},
+		{"3 ?? 1 + true", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 // TestIfElseExpressions tests the evaluation of If/Else conditionals
 func TestIfElseExpressions(t *testing.T) {
 
@@ -267,6 +367,25 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
+// TestEnvironmentTopLevelReturn tests that an environment's top-level return setting overrides TopLevelReturn for programs evaluated in it, without changing other evaluations
+func TestEnvironmentTopLevelReturn(t *testing.T) {
+	env := object.NewEnvironment()
+	env.SetTopLevelReturn(false)
+
+	testObject(t, testEvalIn("return 5; 6", env), errorMessage("return outside of a function"))
+	testObject(t, testEvalIn("let f = fn() { return 5; 6 }; f()", env), 5)
+	testObject(t, testEval("return 5; 6"), 5)
+
+	env = object.NewEnvironment()
+	env.SetTopLevelReturn(true)
+
+	defer func() { TopLevelReturn = true }()
+	TopLevelReturn = false
+
+	testObject(t, testEvalIn("return 5; 6", env), 5)
+	testObject(t, testEval("return 5; 6"), errorMessage("return outside of a function"))
+}
+
 // TestErrorHandling tests the evaluation of error objects and error message handling
 func TestErrorHandling(t *testing.T) {
 
@@ -279,6 +398,18 @@ func TestErrorHandling(t *testing.T) {
 			"8 + false;",
 			"type mismatch: INTEGER + BOOLEAN",
 		},
+		{
+			"5 / 0",
+			"division by zero",
+		},
+		{
+			"let zero = 0; 10 / zero; 1",
+			"division by zero",
+		},
+		{
+			"10 % 0",
+			"modulo by zero",
+		},
 		{
 			"8 + true; 8;",
 			"type mismatch: INTEGER + BOOLEAN",
@@ -431,6 +562,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("five")`, 4},
 		{`len("Hulk Smash!")`, 11},
+		{`len("héllo")`, 5},
+		{`len(["héllo"])`, 1},
 		{`len(8)`, "argument to 'len' not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 		{`let arr = [4, 5 * 5, 32]; len(arr)`, 3},
@@ -562,7 +695,8 @@ func TestHashLiterals(t *testing.T) {
 		"thr" + "ee": 6/2,
 		4: 4,
 		true: 5,
-		false: 6	
+		false: 6,
+		null: 7
 	}`
 
 	evaluated := testEval(input)
@@ -580,6 +714,7 @@ func TestHashLiterals(t *testing.T) {
 		(&object.Integer{Value: 4}).HashKey():      4,
 		TRUE.HashKey():                             5,
 		FALSE.HashKey():                            6,
+		NULL.HashKey():                             7,
 	}
 
 	if len(result.Pairs) != len(expected) {
@@ -627,6 +762,18 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{false: 5}[false]`,
 			5,
 		},
+		{
+			`{null: 5}[null]`,
+			5,
+		},
+		{
+			`{null: 5}[false]`,
+			nil,
+		},
+		{
+			`{0: 5}[null]`,
+			nil,
+		},
 	}
 
 	for _, tt := range tests {
@@ -640,3 +787,2368 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+// TestIndexExpressionsWithDefault tests that an index default is returned only when the key or index is missing
+func TestIndexExpressionsWithDefault(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"k": 5}["k", 0]`, 5},
+		{`{"k": 5}["missing", 0]`, 0},
+		{`let h = {"a": 1}; h["b", 2 * 3]`, 6},
+		{`{"k": null}["k", 0]`, nil},
+		{`{}["k", null]`, nil},
+		{`[1, 2, 3][1, 0]`, 2},
+		{`[1, 2, 3][10, 0]`, 0},
+		{`[1, 2, 3][-1, 0]`, 0},
+		// The default is not evaluated when the key is present
+		{`{"k": 5}["k", undefinedName]`, 5},
+		{`{"k": 5}[fn(x) {x}, 0]`, "Unusable as hash key: FUNCTION"},
+		{`5[0, 1]`, "Index operator not supported: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+// TestConcatStrings tests joining an array of strings with the concatStrings builtin
+func TestConcatStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`concatStrings(["Peanut", " ", "Butter"])`, "Peanut Butter"},
+		{`concatStrings([])`, ""},
+		{`concatStrings(["solo"])`, "solo"},
+		{`let parts = ["a", "b"]; concatStrings(push(parts, "c"))`, "abc"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{`concatStrings("abc")`, "argument to 'concatStrings' must be an ARRAY, got STRING"},
+		{`concatStrings(["a", 1])`, "element 1 of 'concatStrings' must be a STRING, got INTEGER"},
+		{`concatStrings([], [])`, "wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tt := range errorTests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q", tt.expected, errObj.Message)
+		}
+	}
+}
+
+// benchmarkParts builds an array of n one-character strings
+func benchmarkParts(n int) []object.Object {
+	parts := make([]object.Object, n)
+
+	for i := range parts {
+		parts[i] = &object.String{Value: "x"}
+	}
+
+	return parts
+}
+
+// BenchmarkStringPlusLoop builds a string by repeatedly applying the + operator, as a Doorkey loop would
+func BenchmarkStringPlusLoop(b *testing.B) {
+	parts := benchmarkParts(1000)
+
+	for i := 0; i < b.N; i++ {
+		var result object.Object = &object.String{Value: ""}
+
+		for _, part := range parts {
+			result = evalStringInfixExpression("+", result, part)
+		}
+	}
+}
+
+// BenchmarkConcatStrings builds the same string with the concatStrings builtin
+func BenchmarkConcatStrings(b *testing.B) {
+	arr := &object.Array{Elements: benchmarkParts(1000)}
+	concat := builtins["concatStrings"]
+
+	for i := 0; i < b.N; i++ {
+		concat.Fn(arr)
+	}
+}
+
+// TestConcatStringsAllocations tests that concatStrings allocates the joined string once, besides the argument slice and the String object holding the result
+func TestConcatStringsAllocations(t *testing.T) {
+	arr := &object.Array{Elements: benchmarkParts(1000)}
+	concat := builtins["concatStrings"]
+
+	if allocs := testing.AllocsPerRun(10, func() { concat.Fn(arr) }); allocs > 3 {
+		t.Errorf("concatStrings allocated too often. got=%v, want=3", allocs)
+	}
+}
+
+// TestIntegerDivisionPolicy tests / between two integers under both division modes
+func TestIntegerDivisionPolicy(t *testing.T) {
+	defer func() { IntegerDivision = TruncatingDivision }()
+
+	IntegerDivision = TruncatingDivision
+	testIntegerObject(t, testEval("7 / 2"), 3)
+	testIntegerObject(t, testEval("-7 / 2"), -3)
+
+	IntegerDivision = FloatDivision
+	evaluated := testEval("7 / 2")
+
+	result, ok := evaluated.(*object.Float)
+	if !ok {
+		t.Fatalf("Object is not a Float. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if result.Value != 3.5 {
+		t.Errorf("Object has the wrong value. got=%g, want=%g", result.Value, 3.5)
+	}
+
+	if result.Inspect() != "3.5" {
+		t.Errorf("Float has the wrong Inspect. got=%q, want=%q", result.Inspect(), "3.5")
+	}
+}
+
+// errorMessage marks an expected value in a test table as an error object's message
+type errorMessage string
+
+// testObject checks an evaluated object against an expected Go value: int, bool, string, nil (NULL), []int, []string, or errorMessage
+func testObject(t *testing.T, obj object.Object, expected interface{}) bool {
+	switch expected := expected.(type) {
+
+	case int:
+		return testIntegerObject(t, obj, int64(expected))
+
+	case float64:
+		return testFloatObject(t, obj, expected)
+
+	case bool:
+		return testBooleanObject(t, obj, expected)
+
+	case nil:
+		return testNullObject(t, obj)
+
+	case string:
+		return testStringObject(t, obj, expected)
+
+	case errorMessage:
+		errObj, ok := obj.(*object.Error)
+		if !ok {
+			t.Errorf("object is not Error. got=%T (%+v)", obj, obj)
+			return false
+		}
+
+		if errObj.Message != string(expected) {
+			t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			return false
+		}
+
+	case []int:
+		array, ok := obj.(*object.Array)
+		if !ok {
+			t.Errorf("object is not an array. got=%T (%+v)", obj, obj)
+			return false
+		}
+
+		if len(array.Elements) != len(expected) {
+			t.Errorf("Wrong number of elements. want=%d, got=%d", len(expected), len(array.Elements))
+			return false
+		}
+
+		for i, expectedElem := range expected {
+			if !testIntegerObject(t, array.Elements[i], int64(expectedElem)) {
+				return false
+			}
+		}
+
+	case []string:
+		array, ok := obj.(*object.Array)
+		if !ok {
+			t.Errorf("object is not an array. got=%T (%+v)", obj, obj)
+			return false
+		}
+
+		if len(array.Elements) != len(expected) {
+			t.Errorf("Wrong number of elements. want=%d, got=%d", len(expected), len(array.Elements))
+			return false
+		}
+
+		for i, expectedElem := range expected {
+			if !testStringObject(t, array.Elements[i], expectedElem) {
+				return false
+			}
+		}
+
+	default:
+		t.Errorf("unsupported expected type %T", expected)
+		return false
+	}
+
+	return true
+}
+
+// testStringObject fails if the object isn't a String with the expected value
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.String)
+
+	if !ok {
+		t.Errorf("Object is not a String. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("String has the wrong value. got=%q, want=%q", result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
+// TestCharsBuiltin tests splitting strings into single character strings
+func TestCharsBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`chars("abc")`, []string{"a", "b", "c"}},
+		{`chars("")`, []string{}},
+		{`chars("héllo")`, []string{"h", "é", "l", "l", "o"}},
+		{`chars("日本")`, []string{"日", "本"}},
+		{`len(chars("héllo"))`, 5},
+		{`chars(5)`, errorMessage("argument to 'chars' must be a STRING, got INTEGER")},
+		{`chars("a", "b")`, errorMessage("wrong number of arguments. got=2, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestStartsWithEndsWith tests the startsWith and endsWith string builtins
+func TestStartsWithEndsWith(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`startsWith("Doorkey", "Door")`, true},
+		{`startsWith("Doorkey", "key")`, false},
+		{`startsWith("Doorkey", "")`, true},
+		{`startsWith("", "D")`, false},
+		{`endsWith("Doorkey", "key")`, true},
+		{`endsWith("Doorkey", "Door")`, false},
+		{`endsWith("Doorkey", "Doorkey")`, true},
+		{`startsWith(1, "D")`, errorMessage("arguments to 'startsWith' must be STRING, got INTEGER and STRING")},
+		{`endsWith("Doorkey", true)`, errorMessage("arguments to 'endsWith' must be STRING, got STRING and BOOLEAN")},
+		{`endsWith("Doorkey")`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		if boolean, ok := tt.expected.(bool); ok {
+			// Results must be the TRUE and FALSE singletons so == comparisons work
+			if evaluated != nativeBoolToBooleanObject(boolean) {
+				t.Errorf("%q did not return the %t singleton. got=%T (%+v)", tt.input, boolean, evaluated, evaluated)
+			}
+
+			continue
+		}
+
+		testObject(t, evaluated, tt.expected)
+	}
+}
+
+// TestPadBuiltins tests padding strings with padLeft and padRight
+func TestPadBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`padLeft("7", 3, "0")`, "007"},
+		{`padRight("ab", 5, ".")`, "ab..."},
+		{`padLeft("é", 3, "·")`, "··é"},
+		{`padLeft("Doorkey", 3, " ")`, "Doorkey"},
+		{`padRight("Doorkey", 7, " ")`, "Doorkey"},
+		{`padRight("", 2, "-")`, "--"},
+		{`padLeft("a", -1, " ")`, "a"},
+		{`padLeft(7, 3, "0")`, errorMessage("first argument to 'padLeft' must be a STRING, got INTEGER")},
+		{`padRight("7", "3", "0")`, errorMessage("second argument to 'padRight' must be an INTEGER, got STRING")},
+		{`padLeft("7", 3, "00")`, errorMessage("third argument to 'padLeft' must be a single character STRING, got 00")},
+		{`padLeft("7", 3, "")`, errorMessage("third argument to 'padLeft' must be a single character STRING, got ")},
+		{`padRight("7", 3)`, errorMessage("wrong number of arguments. got=2, want=3")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestStrRepeatBuiltin tests repeating strings with strRepeat
+func TestStrRepeatBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`strRepeat("ab", 3)`, "ababab"},
+		{`strRepeat("x", 0)`, ""},
+		{`strRepeat("", 5)`, ""},
+		{`strRepeat("x", -1)`, errorMessage("second argument to 'strRepeat' must not be negative, got -1")},
+		{`strRepeat(1, 2)`, errorMessage("first argument to 'strRepeat' must be a STRING, got INTEGER")},
+		{`strRepeat("x", "2")`, errorMessage("second argument to 'strRepeat' must be an INTEGER, got STRING")},
+		{`strRepeat("x")`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestReadLineBuiltin tests that successive readLine calls return each line of the input, then null
+func TestReadLineBuiltin(t *testing.T) {
+	env := object.NewEnvironment()
+	env.SetIO(strings.NewReader("first line\nsecond line\r\nlast line"), os.Stdout)
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`readLine()`, "first line"},
+		{`readLine()`, "second line"},
+		{`readLine()`, "last line"},
+		{`readLine()`, nil},
+		{`readLine("prompt")`, errorMessage("wrong number of arguments. got=1, want=0")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEvalIn(tt.input, env), tt.expected)
+	}
+
+	// Functions and blocks read from the input of the environment they're in
+	env = object.NewEnvironment()
+	env.SetIO(strings.NewReader("a\nb\n"), os.Stdout)
+	testObject(t, testEvalIn(`let x = readLine(); let f = fn() { readLine() }; x + f()`, env), "ab")
+
+	// builtin() returns a readLine for the environment it's called in
+	env = object.NewEnvironment()
+	env.SetIO(strings.NewReader("c\n"), os.Stdout)
+	testObject(t, testEvalIn(`let readLine = 1; builtin("readLine")()`, env), "c")
+}
+
+// TestReadLineSandbox tests that readLine is disabled in sandbox mode
+func TestReadLineSandbox(t *testing.T) {
+	defer func() { Sandbox = false }()
+
+	env := object.NewEnvironment()
+	env.SetIO(strings.NewReader("secret\n"), os.Stdout)
+	Sandbox = true
+
+	testObject(t, testEvalIn(`readLine()`, env), errorMessage("'readLine' is not available in sandbox mode"))
+}
+
+// TestPutsOutput tests that puts writes each argument to its environment's output
+func TestPutsOutput(t *testing.T) {
+	var out bytes.Buffer
+	env := object.NewEnvironment()
+	env.SetIO(strings.NewReader(""), &out)
+
+	testNullObject(t, testEvalIn(`puts("Hulk", 8, [1, 2])`, env))
+
+	if out.String() != "Hulk\n8\n[1, 2]\n" {
+		t.Errorf("puts wrote the wrong output. got=%q", out.String())
+	}
+}
+
+// TestFriendlyTypeNames tests that error messages use lowercase type names only when FriendlyTypeNames is on
+func TestFriendlyTypeNames(t *testing.T) {
+	defer func() { FriendlyTypeNames = false }()
+
+	tests := []struct {
+		input    string
+		standard string
+		friendly string
+	}{
+		{"8 + false;", "type mismatch: INTEGER + BOOLEAN", "type mismatch: integer + boolean"},
+		{"-true", "Illegal prefix operation, expected integer, received: -BOOLEAN", "Illegal prefix operation, expected integer, received: -boolean"},
+		{`len(8)`, "argument to 'len' not supported, got INTEGER", "argument to 'len' not supported, got integer"},
+		{`push(len, 1)`, "argument to 'push' must be an ARRAY, got BUILTIN", "argument to 'push' must be an ARRAY, got builtin function"},
+		// Non-type arguments are untouched
+		{`strRepeat("x", -1)`, "second argument to 'strRepeat' must not be negative, got -1", "second argument to 'strRepeat' must not be negative, got -1"},
+		{"foobar", "Identifier not found: foobar", "Identifier not found: foobar"},
+	}
+
+	for _, tt := range tests {
+		FriendlyTypeNames = false
+		testObject(t, testEval(tt.input), errorMessage(tt.standard))
+
+		FriendlyTypeNames = true
+		testObject(t, testEval(tt.input), errorMessage(tt.friendly))
+	}
+}
+
+// TestNegativeZero tests that -0 equals 0 and reuses the ZERO object
+func TestNegativeZero(t *testing.T) {
+	testIntegerObject(t, testEval("-0"), 0)
+	testBooleanObject(t, testEval("-0 == 0"), true)
+	testBooleanObject(t, testEval("-(5 - 5) == 0"), true)
+
+	first := testEval("-0")
+	second := testEval("let z = 0; -z")
+
+	if first != ZERO || second != ZERO {
+		t.Errorf("-0 did not return the ZERO object. got=%p and %p, want=%p", first, second, ZERO)
+	}
+}
+
+// TestSequenceExpressions tests that each expression in a sequence is evaluated in order and the last value is returned
+func TestSequenceExpressions(t *testing.T) {
+	var out bytes.Buffer
+	env := object.NewEnvironment()
+	env.SetIO(strings.NewReader(""), &out)
+
+	testIntegerObject(t, testEvalIn(`(puts("a"), 5)`, env), 5)
+
+	if out.String() != "a\n" {
+		t.Errorf("puts was not evaluated before the last expression. output=%q", out.String())
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`(1, 2, 3)`, 3},
+		{`(1, 2) * 4`, 8},
+		{`let x = 2; (x, x * 10)`, 20},
+		{`(1 + true, puts("never"))`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+
+	if out.String() != "a\n" {
+		t.Errorf("expressions after an error were evaluated. output=%q", out.String())
+	}
+}
+
+// TestClampBuiltin tests bounding integers to a range with clamp
+func TestClampBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`clamp(-5, 0, 10)`, 0},
+		{`clamp(5, 0, 10)`, 5},
+		{`clamp(15, 0, 10)`, 10},
+		{`clamp(0, 0, 10)`, 0},
+		{`clamp(10, 0, 10)`, 10},
+		{`clamp(3, 7, 7)`, 7},
+		{`clamp(5, 10, 0)`, errorMessage("invalid bounds for 'clamp', lo=10 is greater than hi=0")},
+		{`clamp("5", 0, 10)`, errorMessage("arguments to 'clamp' must be INTEGER, got STRING")},
+		{`clamp(5, 0)`, errorMessage("wrong number of arguments. got=2, want=3")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestGcdLcmBuiltins tests the greatest common divisor and least common multiple builtins
+func TestGcdLcmBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`gcd(12, 8)`, 4},
+		{`gcd(8, 12)`, 4},
+		{`gcd(17, 5)`, 1},
+		{`gcd(-12, 8)`, 4},
+		{`gcd(0, 5)`, 5},
+		{`gcd(5, 0)`, 5},
+		{`gcd(0, 0)`, 0},
+		{`lcm(4, 6)`, 12},
+		{`lcm(-4, 6)`, 12},
+		{`lcm(7, 1)`, 7},
+		{`lcm(0, 6)`, 0},
+		{`lcm(0, 0)`, 0},
+		{`lcm(9223372036854775807, 2)`, errorMessage("integer overflow in 'lcm'")},
+		{`gcd(-9223372036854775807 - 1, 0)`, errorMessage("integer overflow in 'gcd'")},
+		{`gcd(12, "8")`, errorMessage("arguments to 'gcd' must be INTEGER, got STRING")},
+		{`lcm(4)`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// testSetObject checks that obj is a set-hash containing exactly the expected elements
+func testSetObject(t *testing.T, obj object.Object, expected ...object.Hashable) bool {
+	set, ok := obj.(*object.Hash)
+	if !ok {
+		t.Errorf("object is not a Hash. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if len(set.Pairs) != len(expected) {
+		t.Errorf("set has the wrong number of elements. want=%d, got=%d (%s)", len(expected), len(set.Pairs), set.Inspect())
+		return false
+	}
+
+	for _, el := range expected {
+		pair, ok := set.Pairs[el.HashKey()]
+		if !ok {
+			t.Errorf("set is missing an element. want=%+v, got=%s", el, set.Inspect())
+			return false
+		}
+
+		if pair.Value != TRUE {
+			t.Errorf("set element doesn't map to true. got=%s", pair.Value.Inspect())
+			return false
+		}
+	}
+
+	return true
+}
+
+// TestSetBuiltins tests building sets and the union, intersect, and difference builtins
+func TestSetBuiltins(t *testing.T) {
+	one := &object.Integer{Value: 1}
+	two := &object.Integer{Value: 2}
+	three := &object.Integer{Value: 3}
+	four := &object.Integer{Value: 4}
+	str := &object.String{Value: "a"}
+
+	testSetObject(t, testEval(`setOf([1, 2, 2, 3, 1])`), one, two, three)
+	testSetObject(t, testEval(`setOf([])`))
+	testSetObject(t, testEval(`setOf([1, "a", true])`), one, str, TRUE)
+	testSetObject(t, testEval(`union(setOf([1, 2]), setOf([2, 3, 4]))`), one, two, three, four)
+	testSetObject(t, testEval(`intersect(setOf([1, 2, 3]), setOf([2, 3, 4]))`), two, three)
+	testSetObject(t, testEval(`intersect(setOf([1]), setOf([2]))`))
+	testSetObject(t, testEval(`difference(setOf([1, 2, 3]), setOf([2, 4]))`), one, three)
+	testSetObject(t, testEval(`difference(setOf([1, 2]), setOf([]))`), one, two)
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`setOf([1, 2])[2]`, true},
+		{`setOf([1, 2])[5]`, nil},
+		{`setOf([[1]])`, errorMessage("Unusable as hash key: ARRAY")},
+		{`setOf(1)`, errorMessage("argument to 'setOf' must be an ARRAY, got INTEGER")},
+		{`union(setOf([1]), [2])`, errorMessage("arguments to 'union' must be HASH, got ARRAY")},
+		{`difference(setOf([1]))`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestFlatMapBuiltin tests mapping each element to an array and flattening the results
+func TestFlatMapBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`flatMap([1, 2, 3], fn(n) { [n, n] })`, []int{1, 1, 2, 2, 3, 3}},
+		{`flatMap([1, 2, 3], fn(n) { [] })`, []int{}},
+		{`flatMap([], fn(n) { [n] })`, []int{}},
+		{`flatMap([[1, 2], [3]], fn(arr) { arr })`, []int{1, 2, 3}},
+		{`flatMap(["ab", "c"], chars)`, []string{"a", "b", "c"}},
+		{`flatMap([1, 2], fn(n) { n })`, errorMessage("function passed to 'flatMap' must return an ARRAY, got INTEGER")},
+		{`flatMap([1], fn(n) { let x = n; })`, errorMessage("function passed to 'flatMap' must return an ARRAY, got NULL")},
+		{`flatMap([1], fn(n) { n + true })`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{`flatMap(1, fn(n) { [n] })`, errorMessage("first argument to 'flatMap' must be an ARRAY, got INTEGER")},
+		{`flatMap([1], 1)`, errorMessage("second argument to 'flatMap' must be a FUNCTION, got INTEGER")},
+		{`flatMap([1])`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestFunctionIdentity tests that functions compare equal only to themselves and can't be ordered
+func TestFunctionIdentity(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let f = fn(x) { x }; f == f`, true},
+		{`let f = fn(x) { x }; f != f`, false},
+		{`let f = fn(x) { x }; let g = f; f == g`, true},
+		{`fn(x) { x } == fn(x) { x }`, false},
+		{`let f = fn(x) { x }; let g = fn(x) { x }; f == g`, false},
+		{`let f = fn(x) { x }; let g = fn(x) { x }; f != g`, true},
+		{`len == len`, true},
+		{`len == first`, false},
+		// Builtins that use the environment's input and output are made once for it
+		{`puts == puts`, true},
+		{`readLine == readLine`, true},
+		{`builtin == builtin`, true},
+		{`builtin("puts") == puts`, true},
+		{`let f = fn() { puts }; f() == puts`, true},
+		{`puts == readLine`, false},
+		{`let f = fn(x) { x }; f == 1`, false},
+		{`let f = fn(x) { x }; let g = fn(x) { x }; f < g`, errorMessage("ordering comparison not supported for functions: FUNCTION < FUNCTION")},
+		{`let f = fn(x) { x }; f > 1`, errorMessage("ordering comparison not supported for functions: FUNCTION > INTEGER")},
+		{`len < first`, errorMessage("ordering comparison not supported for functions: BUILTIN < BUILTIN")},
+		{`let f = fn(x) { x }; f + 1`, errorMessage("type mismatch: FUNCTION + INTEGER")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestStrictConditions tests that non-boolean conditions are errors only in strict mode
+func TestStrictConditions(t *testing.T) {
+	defer func() { StrictConditions = false }()
+
+	tests := []struct {
+		input   string
+		lenient interface{}
+		strict  interface{}
+	}{
+		{"if (0) { 10 } else { 20 }", 10, errorMessage("condition must be boolean, got INTEGER")},
+		{"if (1) { 10 }", 10, errorMessage("condition must be boolean, got INTEGER")},
+		{`if ("") { 10 }`, 10, errorMessage("condition must be boolean, got STRING")},
+		{"if (null) { 10 } else { 20 }", 20, errorMessage("condition must be boolean, got NULL")},
+		{"if (1 < 2) { 10 }", 10, 10},
+		{"if (false) { 10 } else { 20 }", 20, 20},
+		{"let ok = true; if (ok) { 10 }", 10, 10},
+	}
+
+	for _, tt := range tests {
+		StrictConditions = false
+		testObject(t, testEval(tt.input), tt.lenient)
+
+		StrictConditions = true
+		testObject(t, testEval(tt.input), tt.strict)
+	}
+}
+
+// TestEmptyFalsy tests that with EmptyFalsy on, empty strings, arrays and hashes are falsy to ! and if alike
+func TestEmptyFalsy(t *testing.T) {
+	defer func() { EmptyFalsy = false }()
+
+	tests := []struct {
+		input   string
+		lenient interface{}
+		strict  interface{}
+	}{
+		{"![]", false, true},
+		{"![1]", false, false},
+		{`!""`, false, true},
+		{`!"x"`, false, false},
+		{"!{}", false, true},
+		{`!{"a": 1}`, false, false},
+		{"!![]", true, false},
+		{"not []", false, true},
+		{"!0", false, false},
+		{"!null", true, true},
+		{"if ([]) { 10 } else { 20 }", 10, 20},
+		{`if ("x") { 10 } else { 20 }`, 10, 10},
+		{`let s = ""; if (s) { 10 } else { 20 }`, 10, 20},
+		{"len([x for x in [[], [1], {}] if x])", 3, 1},
+		{"let i = 0; let items = [1, 2]; while (items) { items = tail(items); i = i + 1 }; i", nil, 2},
+	}
+
+	for _, tt := range tests {
+		EmptyFalsy = false
+		if tt.lenient != nil { // nil when the input doesn't end without EmptyFalsy
+			testObject(t, testEval(tt.input), tt.lenient)
+		}
+
+		EmptyFalsy = true
+		testObject(t, testEval(tt.input), tt.strict)
+	}
+}
+
+// testHashField checks that a hash maps the string key to the expected value
+func testHashField(t *testing.T, obj object.Object, key string, expected interface{}) bool {
+	hash, ok := obj.(*object.Hash)
+	if !ok {
+		t.Errorf("object is not a Hash. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	pair, ok := hash.Pairs[(&object.String{Value: key}).HashKey()]
+	if !ok {
+		t.Errorf("hash has no %q key. got=%s", key, hash.Inspect())
+		return false
+	}
+
+	return testObject(t, pair.Value, expected)
+}
+
+// TestDescribeBuiltin tests the metadata hash returned by describe
+func TestDescribeBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string]interface{}
+	}{
+		{`describe([1, 2, 3])`, map[string]interface{}{"type": "ARRAY", "length": 3}},
+		{`describe("héllo")`, map[string]interface{}{"type": "STRING", "length": 5}},
+		{`describe({"a": 1})`, map[string]interface{}{"type": "HASH", "length": 1}},
+		{`describe(42)`, map[string]interface{}{"type": "INTEGER"}},
+		{`describe(true)`, map[string]interface{}{"type": "BOOLEAN"}},
+		{`describe(null)`, map[string]interface{}{"type": "NULL"}},
+		{`describe(fn(x) { x })`, map[string]interface{}{"type": "FUNCTION"}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		hash, ok := evaluated.(*object.Hash)
+		if !ok {
+			t.Errorf("object is not a Hash. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if len(hash.Pairs) != len(tt.expected) {
+			t.Errorf("%s has the wrong number of fields. want=%d, got=%s", tt.input, len(tt.expected), hash.Inspect())
+		}
+
+		for key, value := range tt.expected {
+			testHashField(t, hash, key, value)
+		}
+	}
+
+	testObject(t, testEval(`describe()`), errorMessage("wrong number of arguments. got=0, want=1"))
+	testObject(t, testEval(`describe([1, 2])["length"]`), 2)
+}
+
+// TestBuiltinAccessor tests retrieving the original builtin after its name is shadowed
+func TestBuiltinAccessor(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let len = 5; len`, 5},
+		{`let len = 5; builtin("len")([1, 2, 3])`, 3},
+		{`let len = fn(x) { 0 }; let originalLen = builtin("len"); len("abc") + originalLen("abc")`, 3},
+		{`builtin("len") == len`, true},
+		{`builtin("builtin")("first")([7, 8])`, 7},
+		{`builtin("nope")`, errorMessage(`no builtin function named "nope"`)},
+		{`builtin(len)`, errorMessage("argument to 'builtin' must be a STRING, got BUILTIN")},
+		{`builtin()`, errorMessage("wrong number of arguments. got=0, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestInternStrings tests that string literals share one object only when InternStrings is on
+func TestInternStrings(t *testing.T) {
+	defer func() { InternStrings = false }()
+
+	input := `["key", "key"]`
+
+	InternStrings = false
+	arr := testEval(input).(*object.Array)
+	if arr.Elements[0] == arr.Elements[1] {
+		t.Errorf("string literals are shared with InternStrings off")
+	}
+
+	InternStrings = true
+	arr = testEval(input).(*object.Array)
+	if arr.Elements[0] != arr.Elements[1] {
+		t.Errorf("string literals are not shared with InternStrings on")
+	}
+
+	testObject(t, testEval(`{"key": 5}["key"]`), 5)
+	testObject(t, testEval(`"a" + "b"`), "ab")
+
+	// An interned literal is looked up rather than allocated, see BenchmarkInternedHashLookup for the timing
+	env := object.NewEnvironment()
+	testEvalIn(`let h = {"key": 5}`, env)
+	program := parser.New(lexer.New(`h["key"]`)).ParseProgram()
+
+	if allocs := testing.AllocsPerRun(10, func() { Eval(program, env) }); allocs != 0 {
+		t.Errorf("interned string literal lookup allocated. got=%v, want=0", allocs)
+	}
+}
+
+// benchmarkHashLookup evaluates a hash index with a string literal key, the case InternStrings is for
+func benchmarkHashLookup(b *testing.B, intern bool) {
+	defer func() { InternStrings = false }()
+	InternStrings = intern
+
+	env := object.NewEnvironment()
+	testEvalIn(`let h = {"key": 5}`, env)
+	program := parser.New(lexer.New(`h["key"]`)).ParseProgram()
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		Eval(program, env)
+	}
+}
+
+// BenchmarkHashLookup looks up a hash with a new string literal each time
+func BenchmarkHashLookup(b *testing.B) {
+	benchmarkHashLookup(b, false)
+}
+
+// BenchmarkInternedHashLookup looks up a hash with an interned string literal, whose hash key is already computed
+func BenchmarkInternedHashLookup(b *testing.B) {
+	benchmarkHashLookup(b, true)
+}
+
+// TestApplyBuiltin tests spreading an array as a function's arguments
+func TestApplyBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`apply(fn(a, b) { a - b }, [10, 4])`, 6},
+		{`apply(fn() { 5 }, [])`, 5},
+		{`let add = fn(a, b, c) { a + b + c }; apply(add, [1, 2, 3])`, 6},
+		{`apply(len, ["four"])`, 4},
+		{`apply(fn(a, b) { a + b }, [1])`, errorMessage("wrong number of arguments to 'apply'. got=1, want=2")},
+		{`apply(fn(a) { a }, [1, 2])`, errorMessage("wrong number of arguments to 'apply'. got=2, want=1")},
+		{`apply(len, [1, 2])`, errorMessage("wrong number of arguments. got=2, want=1")},
+		{`apply(1, [1])`, errorMessage("first argument to 'apply' must be a FUNCTION, got INTEGER")},
+		{`apply(fn(a) { a }, 1)`, errorMessage("second argument to 'apply' must be an ARRAY, got INTEGER")},
+		{`apply(fn(a) { a })`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestComments tests that programs with comments evaluate
+func TestComments(t *testing.T) {
+	testObject(t, testEval("let x = 5; // set x\nx * 2 // double it"), 10)
+}
+
+// TestLetInExpression tests that a let-in binding is only visible in its body
+func TestLetInExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let x = 5 in x * 2`, 10},
+		{`(let x = 5 in x * 2) + 1`, 11},
+		{`let x = 1 in let y = 2 in x + y`, 3},
+		{`let x = 1; let y = let x = 10 in x; x + y`, 11},
+		{`let x = 5 in x; x`, errorMessage("Identifier not found: x")},
+		{`let x = y in x`, errorMessage("Identifier not found: y")},
+		{`let f = fn(n) { let d = n * 2 in d + 1 }; f(3)`, 7},
+		{`let x = 1; let x = x + 1 in x`, 2},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestRecursiveFunctions tests that a function bound by let, let-in or const can call itself, and functions bound one after another can call each other
+func TestRecursiveFunctions(t *testing.T) {
+	fact := `let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };`
+	evenOdd := `let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+	let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{fact + `fact(5)`, 120},
+		{evenOdd + `isEven(10)`, true},
+		{evenOdd + `isOdd(7)`, true},
+		{evenOdd + `isOdd(4)`, false},
+		{`let outer = fn() { ` + evenOdd + ` isEven(3) }; outer()`, false},
+		{`let f = fn(x) { let g = fn(n) { if (n == 0) { x } else { g(n - 1) } }; g(3) }; f(9)`, 9},
+		{`let fib = fn(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } } in fib(10)`, 55},
+		{`const fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(4)`, 24},
+		{`let h = {"f": fn(n) { if (n == 0) { 0 } else { h["f"](n - 1) + 1 } }}; h["f"](3)`, 3},
+		{fact + `let f = fact; let fact = fn(n) { 0 }; f(5)`, 0},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestPipeExpression tests that x |> f calls f with x
+func TestPipeExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`[1, 2, 3] |> reverse |> first`, 3},
+		{`5 |> fn(x) { x * 2 }`, 10},
+		{`let double = fn(x) { x * 2 }; 1 + 2 |> double |> double`, 12},
+		{`"four" |> len`, 4},
+		{`let add = fn(a) { fn(b) { a + b } }; 1 |> add(2)`, 3},
+		{`5 |> 10`, errorMessage("right side of '|>' must be a FUNCTION, got INTEGER")},
+		{`5 |> first`, errorMessage("argument to 'first' must be an ARRAY, got INTEGER")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestReverseBuiltin tests reversing an array
+func TestReverseBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`reverse([1, 2, 3])`, []int{3, 2, 1}},
+		{`reverse([])`, []int{}},
+		{`let a = [1, 2]; reverse(a); a`, []int{1, 2}},
+		{`reverse(1)`, errorMessage("argument to 'reverse' must be an ARRAY, got INTEGER")},
+		{`reverse()`, errorMessage("wrong number of arguments. got=0, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestFloatExpression tests float literals, float arithmetic, and promotion of integers mixed with floats
+func TestFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"3.14", 3.14},
+		{"0.5", 0.5},
+		{"-2.5", -2.5},
+		{"1.5 + 2.25", 3.75},
+		{"5.5 - 0.5", 5.0},
+		{"1.5 * 4.0", 6.0},
+		{"7.0 / 2.0", 3.5},
+		{"1 + 0.5", 1.5},
+		{"0.5 * 4", 2.0},
+		{"7 / 2.0", 3.5},
+		{"1.5 < 2", true},
+		{"2.5 > 3.5", false},
+		{"2.0 == 2", true},
+		{"0.1 != 0.1", false},
+		{"7 / 2", 3},
+		{"1.5 + true", errorMessage("type mismatch: FLOAT + BOOLEAN")},
+		{`1.5 + "a"`, errorMessage("type mismatch: FLOAT + STRING")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+
+	if inspect := testEval("1.5 + 2.5").Inspect(); inspect != "4.0" {
+		t.Errorf("wrong Inspect for a whole float. got=%q, want=%q", inspect, "4.0")
+	}
+}
+
+// TestPartialApplication tests that calling a function with too few arguments returns a function waiting for the rest
+func TestPartialApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let add = fn(a, b) { a + b }; let inc = add(1); inc(5);`, 6},
+		{`let add = fn(a, b) { a + b }; add(1, 5);`, 6},
+		{`let add = fn(a, b) { a + b }; let inc = add(1); inc(5) + inc(10);`, 17},
+		{`let sub = fn(a, b, c) { a - b - c }; sub(10)(3)(2);`, 5},
+		{`let sub = fn(a, b, c) { a - b - c }; sub(10, 3)(2);`, 5},
+		{`let sub = fn(a, b, c) { a - b - c }; sub(10)(3, 2);`, 5},
+		{`let add = fn(a, b) { a + b }; add()(1, 2);`, 3},
+		{`let add = fn(a, b) { a + b }; [1, 2] |> first |> add(10);`, 11},
+		{`let add = fn(a, b) { a + b }; add(1, 2, 3);`, errorMessage("wrong number of arguments. got=3, want=2")},
+		{`let add = fn(a, b) { a + b }; add(1)(2, 3);`, errorMessage("wrong number of arguments. got=2, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+
+	partial, ok := testEval(`let add = fn(a, b) { a + b }; add(1)`).(*object.Function)
+	if !ok {
+		t.Fatalf("partially applied function is not a Function")
+	}
+
+	if len(partial.Parameters) != 1 || partial.Parameters[0].Value != "b" {
+		t.Errorf("partially applied function has the wrong parameters. got=%v", partial.Parameters)
+	}
+}
+
+// TestWhileExpression tests while loops, their result, and returns from inside the body
+func TestWhileExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let i = 0; while (i < 5) { i = i + 1; }; i`, 5},
+		{`let i = 0; while (i < 3) { i = i + 1; i * 10 }`, 30},
+		{`while (false) { 1 }`, nil},
+		{`let i = 0; let last = while (i < 3) { i = i + 1; i * 10 }; last`, 30},
+		{`let last = while (false) { 1 }; last`, nil},
+		{`let i = 0; while (i < 3) { i = i + 1; let j = i; }`, nil},
+		{`let f = fn() { let i = 0; while (true) { i = i + 1; if (i == 4) { return i; } } }; f()`, 4},
+		{`let f = fn() { while (true) { return 7; }; 1 }; f()`, 7},
+		{`while (true) { 1 + true }`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{`while (x) { 1 }`, errorMessage("Identifier not found: x")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestStringFormatting tests formatting a string with % and a single value or an array of values
+func TestStringFormatting(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let count = 3; "%d items" % count`, "3 items"},
+		{`"%s!" % "hi"`, "hi!"},
+		{`"%s" % null`, "null"},
+		{`"%s" % true`, "true"},
+		{`let key = "x"; let val = 5; "%s=%d" % [key, val]`, "x=5"},
+		{`"%s and %s" % ["a", [1, 2]]`, "a and [1, 2]"},
+		{`"%s" % [[1, 2]]`, "[1, 2]"},
+		{`"%f" % 2.5`, "2.500000"},
+		{`"%.2f" % 3.14159`, "3.14"},
+		{`"%.f" % 2.6`, "3"},
+		{`"%.1f" % 2`, "2.0"},
+		{`"100%%" % []`, "100%"},
+		{`"%d%%" % 50`, "50%"},
+		{`"no verbs" % []`, "no verbs"},
+		{`"héllo %s" % "wörld"`, "héllo wörld"},
+		{`"%d" % "3"`, errorMessage("format verb %d expects an INTEGER, got STRING")},
+		{`"%d" % 1.5`, errorMessage("format verb %d expects an INTEGER, got FLOAT")},
+		{`"%.2f" % "x"`, errorMessage("format verb %.2f expects a number, got STRING")},
+		{`"%d and %d" % [1]`, errorMessage("not enough values for format string. got=1")},
+		{`"%d" % [1, 2]`, errorMessage("too many values for format string. got=2, want=1")},
+		{`"no verbs" % 1`, errorMessage("too many values for format string. got=1, want=0")},
+		{`"%q" % 1`, errorMessage("unsupported format verb: %q")},
+		{`"%.2d" % 1`, errorMessage("unsupported format verb: %.2d")},
+		{`"%5d" % 1`, errorMessage("unsupported format verb: %5d")},
+		{`"%-10.2f" % 1.5`, errorMessage("unsupported format verb: %-10.2f")},
+		{`"%05" % 1`, errorMessage("unsupported format verb: %05")},
+		{`"50%" % []`, errorMessage("format string ends with an incomplete verb: %")},
+		{`1 % "a"`, errorMessage("type mismatch: INTEGER % STRING")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestBlockScope tests that a let inside an if or while body is local to the block, while assignments and reads still reach the enclosing scope
+func TestBlockScope(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`if (true) { let x = 5; }; x`, errorMessage("Identifier not found: x")},
+		{`if (false) { 1 } else { let y = 2; }; y`, errorMessage("Identifier not found: y")},
+		{`let i = 0; while (i < 2) { let inner = i; i = i + 1 }; inner`, errorMessage("Identifier not found: inner")},
+		{`let x = 1; if (true) { let x = 5; }; x`, 1},
+		{`let x = 1; if (true) { let x = 5; x }`, 5},
+		{`let x = 1; if (true) { x = 5; }; x`, 5},
+		{`let x = 1; if (true) { x + 1 }`, 2},
+		{`let i = 0; let total = 0; while (i < 3) { let step = i * 2; total = total + step; i = i + 1 }; total`, 6},
+		{`let f = fn() { if (true) { let local = 3; }; local }; f()`, errorMessage("Identifier not found: local")},
+		{`const PI = 3; if (true) { let PI = 4; PI }`, 4},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestStrictWhileCondition tests that StrictConditions applies to while loops
+func TestStrictWhileCondition(t *testing.T) {
+	StrictConditions = true
+	defer func() { StrictConditions = false }()
+
+	testObject(t, testEval(`while (1) { 1 }`), errorMessage("condition must be boolean, got INTEGER"))
+	testObject(t, testEval(`while (false) { 1 }`), nil)
+}
+
+// TestNormalizeIndex tests the index normalization shared by slice, substr, take, and drop
+func TestNormalizeIndex(t *testing.T) {
+	tests := []struct {
+		index    int64
+		length   int
+		expected int
+	}{
+		{0, 5, 0},
+		{2, 5, 2},
+		{5, 5, 5},
+		{6, 5, 5},
+		{100, 5, 5},
+		{-1, 5, 4},
+		{-5, 5, 0},
+		{-6, 5, 0},
+		{-100, 5, 0},
+		{0, 0, 0},
+		{-1, 0, 0},
+		{1, 0, 0},
+		// Beyond 32 bits, these would wrap to 1 and -1 if converted to a 32-bit int before clamping
+		{1<<32 + 1, 5, 5},
+		{-(1<<32 + 1), 5, 0},
+		{math.MaxInt64, 5, 5},
+		{math.MinInt64, 5, 0},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeIndex(tt.index, tt.length); got != tt.expected {
+			t.Errorf("normalizeIndex(%d, %d) = %d, want=%d", tt.index, tt.length, got, tt.expected)
+		}
+	}
+}
+
+// TestSliceBuiltins tests slice, substr, take, and drop, which share their index handling
+func TestSliceBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`slice([1, 2, 3, 4], 1, 3)`, []int{2, 3}},
+		{`slice([1, 2, 3, 4], 1)`, []int{2, 3, 4}},
+		{`slice([1, 2, 3, 4], -2)`, []int{3, 4}},
+		{`slice([1, 2, 3, 4], 0, -1)`, []int{1, 2, 3}},
+		{`slice([1, 2, 3, 4], -10, 10)`, []int{1, 2, 3, 4}},
+		{`slice([1, 2, 3, 4], 3, 1)`, []int{}},
+		{`slice([], 0, 1)`, []int{}},
+		{`slice("abc", 0)`, errorMessage("first argument to 'slice' must be an ARRAY, got STRING")},
+		{`slice([1], "a")`, errorMessage("second argument to 'slice' must be an INTEGER, got STRING")},
+		{`slice([1], 0, "a")`, errorMessage("third argument to 'slice' must be an INTEGER, got STRING")},
+		{`slice([1])`, errorMessage("wrong number of arguments. got=1, want=2 or 3")},
+
+		{`substr("doorkey", 0, 4)`, "door"},
+		{`substr("doorkey", 4)`, "key"},
+		{`substr("doorkey", -3)`, "key"},
+		{`substr("doorkey", 0, -3)`, "door"},
+		{`substr("doorkey", -100, 100)`, "doorkey"},
+		{`substr("doorkey", 5, 2)`, ""},
+		{`substr("héllo", 0, 2)`, "hé"},
+		{`substr("héllo", 1, 2)`, "é"},
+		{`substr("héllo", -4)`, "éllo"},
+		{`substr("日本語", 1)`, "本語"},
+		{`substr("a🎉b", 1, 2)`, "🎉"},
+		{`let s = "naïve café"; substr(s, 0, len(s))`, "naïve café"},
+		{`substr("héllo", 0, 2) == "héllo"[0] + "héllo"[1]`, true},
+		{`substr([1], 0)`, errorMessage("first argument to 'substr' must be a STRING, got ARRAY")},
+		{`substr("a", true)`, errorMessage("second argument to 'substr' must be an INTEGER, got BOOLEAN")},
+
+		{`take([1, 2, 3], 2)`, []int{1, 2}},
+		{`take([1, 2, 3], 0)`, []int{}},
+		{`take([1, 2, 3], 10)`, []int{1, 2, 3}},
+		{`take([1, 2, 3], -1)`, []int{1, 2}},
+		{`take([1, 2, 3], -10)`, []int{}},
+		{`take(1, 1)`, errorMessage("first argument to 'take' must be an ARRAY, got INTEGER")},
+		{`take([1], "a")`, errorMessage("second argument to 'take' must be an INTEGER, got STRING")},
+
+		{`drop([1, 2, 3], 2)`, []int{3}},
+		{`drop([1, 2, 3], 0)`, []int{1, 2, 3}},
+		{`drop([1, 2, 3], 10)`, []int{}},
+		{`drop([1, 2, 3], -1)`, []int{3}},
+		{`drop([1, 2, 3], -10)`, []int{1, 2, 3}},
+		{`drop([1, 2, 3])`, errorMessage("wrong number of arguments. got=1, want=2")},
+
+		{`let a = [1, 2, 3]; let b = take(a, 2); push(b, 9); a`, []int{1, 2, 3}},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestTypeAnnotations tests that let type annotations are ignored by default and checked when EnforceTypeAnnotations is on
+func TestTypeAnnotations(t *testing.T) {
+	testObject(t, testEval(`let x: int = "s"; x`), "s")
+
+	EnforceTypeAnnotations = true
+	defer func() { EnforceTypeAnnotations = false }()
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let x: int = 5; x`, 5},
+		{`let x: string = "s"; x`, "s"},
+		{`let x: bool = 1 < 2; x`, true},
+		{`let x: array = [1]; len(x)`, 1},
+		{`let f: function = fn(a) { a }; f(3)`, 3},
+		{`let f: function = len; f("ab")`, 2},
+		{`let x: int = 5 in x * 2`, 10},
+		{`let x = "untyped"; x`, "untyped"},
+		{`let x: int = "s";`, errorMessage("type annotation mismatch: x is int, got STRING")},
+		{`let x: string = 5 in x`, errorMessage("type annotation mismatch: x is string, got INTEGER")},
+		{`let x: number = 5;`, errorMessage("unknown type annotation: number")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestDefaultParameters tests calling functions with and without their defaulted arguments
+func TestDefaultParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let f = fn(x, y = 10) { x + y }; f(5)`, 15},
+		{`let f = fn(x, y = 10) { x + y }; f(5, 1)`, 6},
+		{`let f = fn(x = 1, y = 2) { x * 10 + y }; f()`, 12},
+		{`let f = fn(x = 1, y = 2) { x * 10 + y }; f(3)`, 32},
+		{`let f = fn(x, y = x * 2) { y }; f(4)`, 8},
+		{`let n = 1; let f = fn(x = n) { x }; let n = 2; f()`, 2},
+		{`let f = fn(a = []) { push(a, 1) }; f(); len(f())`, 1},
+		{`let f = fn(x, y, z = 3) { x + y + z }; f(1)(2)`, 6},
+		{`let f = fn(x, y = 10) { x + y }; f(1, 2, 3)`, errorMessage("wrong number of arguments. got=3, want=2")},
+		{`let f = fn(x, y = z) { x + y }; f(1)`, errorMessage("Identifier not found: z")},
+		{`let f = fn(x, y = 10) { x + y }; apply(f, [1])`, 11},
+		{`let f = fn(x, y = 10) { x + y }; apply(f, [])`, errorMessage("wrong number of arguments to 'apply'. got=0, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+
+	if inspect := testEval(`fn(x, y = 10) { x + y }`).Inspect(); !strings.HasPrefix(inspect, "fn(x, y = 10)") {
+		t.Errorf("function Inspect doesn't show the default. got=%q", inspect)
+	}
+}
+
+// TestAssignExpression tests updating existing bindings without let
+func TestAssignExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let x = 1; x = 5; x`, 5},
+		{`let x = 1; x = x + 1; x`, 2},
+		{`let x = 1; x = 7`, 7},
+		{`let x = 1; let y = 2; x = y = 3; x + y`, 6},
+		{`let x = 1; let f = fn() { x = 10 }; f(); x`, 10},
+		{`let counter = fn() { let n = 0; fn() { n = n + 1 } }; let c = counter(); c(); c(); c()`, 3},
+		{`let i = 0; let sum = 0; while (i < 4) { i = i + 1; sum = sum + i }; sum`, 10},
+		{`let x = 1; let f = fn(x) { x = 5; x }; f(2) + x`, 6},
+		{`y = 5`, errorMessage("identifier not found: y")},
+		{`let x = 1; x = 1 + true`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		// Elements of arrays and hashes are set in place
+		{`let a = [1, 2, 3]; a[1] = 5; a`, []int{1, 5, 3}},
+		{`let a = [1, 2]; a[0] = 7`, 7},
+		{`let a = [1]; let b = a; b[0] = 2; a[0]`, 2},
+		{`let a = [[1, 2]]; a[0][1] = 3; a[0]`, []int{1, 3}},
+		{`let h = {"a": 1}; h["a"] = 2; h["b"] = 3; h["a"] * 10 + h["b"]`, 23},
+		{`let h = {"b": 1}; h["a"] = 2; values(h)`, []int{1, 2}},
+		{`let h = {}; let a = [0]; h[1] = a[0] = 5; h[1] + a[0]`, 10},
+		{`let a = [1]; a[1] = 2`, errorMessage("array index out of range: 1")},
+		{`let a = [1]; a[-1] = 2`, errorMessage("array index out of range: -1")},
+		{`let a = [1]; a["x"] = 2`, errorMessage("array index must be an INTEGER, got STRING")},
+		{`let h = {}; h[[1]] = 2`, errorMessage("Unusable as hash key: ARRAY")},
+		{`let s = "abc"; s[0] = "x"`, errorMessage("Index assignment not supported: STRING")},
+		{`let a = [1]; a[0] = 1 + true; a`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{`b[0] = 1`, errorMessage("Identifier not found: b")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestConstStatements tests that const bindings can be read but not reassigned or redeclared
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`const PI = 3; PI`, 3},
+		{`const PI = 3; PI * 2`, 6},
+		{`const PI = 3; PI = 4`, errorMessage("cannot reassign constant: PI")},
+		{`const PI = 3; PI = 4; PI`, errorMessage("cannot reassign constant: PI")},
+		{`const PI = 3; const PI = 4`, errorMessage("cannot reassign constant: PI")},
+		{`const PI = 3; let PI = 4`, errorMessage("cannot reassign constant: PI")},
+		{`const PI = 3; let f = fn() { PI = 4 }; f()`, errorMessage("cannot reassign constant: PI")},
+		{`const PI = 3; let f = fn() { let PI = 4; PI }; f() + PI`, 7},
+		{`const PI = 3; let f = fn() { let PI = 4; PI = 5; PI }; f()`, 5},
+		{`let x = 1; const x = 2; x`, 2},
+		{`let x = 1; const x = 2; x = 3`, errorMessage("cannot reassign constant: x")},
+		{`const x = 1 + true`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{`const double = fn(x) { x * 2 }; double(4)`, 8},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestNamedArguments tests binding call arguments by parameter name
+func TestNamedArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let f = fn(x, y) { x - y }; f(y = 3, x = 10)`, 7},
+		{`let f = fn(x, y) { x - y }; f(x = 10, y = 3)`, 7},
+		{`let f = fn(x, y, z) { x * 100 + y * 10 + z }; f(1, z = 3, y = 2)`, 123},
+		{`let f = fn(x, y = 10, z = 20) { x + y + z }; f(1, z = 0)`, 11},
+		{`let f = fn(x = 1, y = 2) { x * 10 + y }; f(y = 5)`, 15},
+		{`let f = fn(x, y = x * 2) { y }; f(x = 4)`, 8},
+		{`let x = 1; let f = fn(x) { x }; f(x = 5); x`, 1},
+		{`let f = fn(x, y) { x - y }; f(z = 1, x = 2)`, errorMessage("unknown parameter name: z")},
+		{`let f = fn(x, y) { x - y }; f(1, x = 2)`, errorMessage("argument x given more than once")},
+		{`let f = fn(x, y) { x - y }; f(x = 1, x = 2)`, errorMessage("argument x given more than once")},
+		{`let f = fn(x, y) { x - y }; f(y = 1)`, errorMessage("missing argument for parameter x")},
+		{`let f = fn(x) { x }; f(1, 2, x = 3)`, errorMessage("wrong number of arguments. got=2, want=1")},
+		{`len(x = "a")`, errorMessage("named arguments are not supported for builtin functions")},
+		{`let f = fn(x) { x }; f(x = 1 + true)`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestErrorTrace tests that errors record the chain of function calls they propagated out of
+func TestErrorTrace(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedInspect string
+	}{
+		{
+			`let inner = fn(x) { x + true }; let middle = fn(x) { inner(x) }; let outer = fn() { middle(1) }; outer()`,
+			"ERROR: type mismatch: INTEGER + BOOLEAN\n  in inner\n  in middle\n  in outer",
+		},
+		{
+			`let f = fn() { first(1) }; f()`,
+			"ERROR: argument to 'first' must be an ARRAY, got INTEGER\n  in f",
+		},
+		{
+			`fn() { -true }()`,
+			"ERROR: Illegal prefix operation, expected integer, received: -BOOLEAN\n  in anonymous function",
+		},
+		{
+			`let make = fn() { fn() { y } }; make()()`,
+			"ERROR: Identifier not found: y\n  in make()",
+		},
+		{
+			`let fact = fn(n) { n + true }; let f = fact; f(1)`,
+			"ERROR: type mismatch: INTEGER + BOOLEAN\n  in fact",
+		},
+		// Errors binding the arguments happen at the call, so the callee gets no frame
+		{
+			`let f = fn(x) { x }; f(y = 1)`,
+			"ERROR: unknown parameter name: y",
+		},
+		{
+			`let f = fn(x, y) { x }; f(1, x = 2)`,
+			"ERROR: argument x given more than once",
+		},
+		{
+			`let f = fn(x, y) { x }; f(y = 2)`,
+			"ERROR: missing argument for parameter x",
+		},
+		{
+			`let f = fn(x) { x }; f(1, 2)`,
+			"ERROR: wrong number of arguments. got=2, want=1",
+		},
+		{
+			`let f = fn(x) { x }; let g = fn() { f(y = 1) }; g()`,
+			"ERROR: unknown parameter name: y\n  in g",
+		},
+		{
+			`first(1)`,
+			"ERROR: argument to 'first' must be an ARRAY, got INTEGER",
+		},
+		{
+			`1 + true`,
+			"ERROR: type mismatch: INTEGER + BOOLEAN",
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Inspect() != tt.expectedInspect {
+			t.Errorf("wrong error Inspect. expected=%q, got=%q", tt.expectedInspect, errObj.Inspect())
+		}
+	}
+}
+
+// TestTimeitBuiltin tests that timeit returns the function's result and a non-negative time. The time itself isn't checked.
+func TestTimeitBuiltin(t *testing.T) {
+	evaluated := testEval(`timeit(fn() { let i = 0; while (i < 100) { i = i + 1 }; i })`)
+
+	testHashField(t, evaluated, "result", 100)
+
+	hash, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("timeit didn't return a Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	ms, ok := hash.Pairs[(&object.String{Value: "ms"}).HashKey()].Value.(*object.Float)
+	if !ok {
+		t.Fatalf("ms is not a Float. got=%+v", hash.Pairs[(&object.String{Value: "ms"}).HashKey()])
+	}
+
+	if ms.Value < 0 {
+		t.Errorf("ms is negative. got=%f", ms.Value)
+	}
+
+	testHashField(t, testEval(`timeit(fn() { let x = 1; })`), "result", nil)
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`timeit(1)`, errorMessage("argument to 'timeit' must be a FUNCTION, got INTEGER")},
+		{`timeit(fn() { 1 + true })`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{`timeit()`, errorMessage("wrong number of arguments. got=0, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+
+	Sandbox = true
+	defer func() { Sandbox = false }()
+
+	testObject(t, testEval(`timeit(fn() { 1 })`), errorMessage("'timeit' is not available in sandbox mode"))
+}
+
+// TestStrBuiltin tests converting values to strings
+func TestStrBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`str(42)`, "42"},
+		{`str(-7)`, "-7"},
+		{`str(true)`, "true"},
+		{`str(false)`, "false"},
+		{`str(null)`, "null"},
+		{`str(2.5)`, "2.5"},
+		{`str("already")`, "already"},
+		{`str([1, 2])`, "[1, 2]"},
+		{`"total: " + str(1 + 2)`, "total: 3"},
+		{`str()`, errorMessage("wrong number of arguments. got=0, want=1")},
+		{`str(1, 2)`, errorMessage("wrong number of arguments. got=2, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestIntBuiltin tests converting strings and floats to integers
+func TestIntBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`int("123")`, 123},
+		{`int("-45")`, -45},
+		{`int("0")`, 0},
+		{`int(7)`, 7},
+		{`int(3.9)`, 3},
+		{`int(-3.9)`, -3},
+		{`int(0.0 / 0.0)`, errorMessage("could not convert NaN to integer")},
+		{`int(1.0 / 0.0)`, errorMessage("could not convert +Inf to integer")},
+		{`int(-1.0 / 0.0)`, errorMessage("could not convert -Inf to integer")},
+		{`int(10000000000000000000.0)`, errorMessage("could not convert 10000000000000000000.0 to integer")},
+		{`int(9223372036854775807.0)`, errorMessage("could not convert 9223372036854776000.0 to integer")},
+		{`int(-9223372036854775808.0)`, -9223372036854775808},
+		{`int(str(42)) + 1`, 43},
+		{`int("abc")`, errorMessage(`could not parse "abc" as integer`)},
+		{`int("1.5")`, errorMessage(`could not parse "1.5" as integer`)},
+		{`int("")`, errorMessage(`could not parse "" as integer`)},
+		{`int("99999999999999999999")`, errorMessage(`could not parse "99999999999999999999" as integer`)},
+		{`int(true)`, errorMessage("argument to 'int' not supported, got BOOLEAN")},
+		{`int()`, errorMessage("wrong number of arguments. got=0, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestVersionBuiltin tests that version returns the interpreter and Go versions
+func TestVersionBuiltin(t *testing.T) {
+	evaluated := testEval(`version()`)
+
+	testHashField(t, evaluated, "version", Version)
+	testHashField(t, evaluated, "go", runtime.Version())
+
+	if hash, ok := evaluated.(*object.Hash); ok && len(hash.Pairs) != 2 {
+		t.Errorf("version hash has the wrong number of pairs. got=%d, want=2", len(hash.Pairs))
+	}
+
+	testObject(t, testEval(`version(1)`), errorMessage("wrong number of arguments. got=1, want=0"))
+}
+
+// TestWithBuiltin tests that with returns an updated deep copy of a hash
+func TestWithBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`with({"a": 1}, "a", 2)["a"]`, 2},
+		{`with({"a": 1}, "b", 2)["b"]`, 2},
+		{`with({"a": 1}, "b", 2)["a"]`, 1},
+		{`let h = {"a": 1}; with(h, "a", 2); h["a"]`, 1},
+		{`with({}, 1, "one")[1]`, "one"},
+		{`with({"a": {"b": [1, 2]}}, "c", 3)["a"]["b"]`, []int{1, 2}},
+		{`with([1], "a", 2)`, errorMessage("first argument to 'with' must be a HASH, got ARRAY")},
+		{`with({}, [1], 2)`, errorMessage("Unusable as hash key: ARRAY")},
+		{`with({}, "a")`, errorMessage("wrong number of arguments. got=2, want=3")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+
+	// Mutating the nested hash and array of the copy leaves the original's alone
+	shared := `let original = {"nested": {"list": [1, 2]}};
+		let updated = with(original, "x", 1);
+		updated["nested"]["list"][0] = 100;
+		updated["nested"]["y"] = 2;`
+
+	testObject(t, testEval(shared+`original["nested"]["list"]`), []int{1, 2})
+	testObject(t, testEval(shared+`original["nested"]["y"]`), nil)
+	testObject(t, testEval(shared+`updated["nested"]["list"]`), []int{100, 2})
+}
+
+// TestFilterBuiltin tests keeping the array elements a predicate accepts
+func TestFilterBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, []int{3, 4}},
+		{`filter([1, 2, 3, 4], fn(x) { x % 2 == 0 })`, []int{2, 4}},
+		{`filter([1, 2, 3], fn(x) { false })`, []int{}},
+		{`filter([], fn(x) { true })`, []int{}},
+		{`filter([1, 2, 3], fn(x) { x })`, []int{1, 2, 3}},
+		{`filter([1, 2, 3], fn(x) { null })`, []int{}},
+		{`filter([1, 2], fn(x) { let y = x; })`, []int{}},
+		{`filter(["a", "", "b"], len)`, []string{"a", "", "b"}},
+		{`let a = [1, 2]; filter(a, fn(x) { x > 1 }); a`, []int{1, 2}},
+		{`filter([1], fn(x) { x + true })`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{`filter(1, fn(x) { true })`, errorMessage("argument to 'filter' must be an ARRAY, got INTEGER")},
+		{`filter([1], 1)`, errorMessage("second argument to 'filter' must be a FUNCTION, got INTEGER")},
+		{`filter([1])`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestReduceBuiltin tests folding arrays with an accumulator
+func TestReduceBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`reduce([1, 2, 3, 4], 0, fn(acc, x) { acc + x })`, 10},
+		{`reduce([1, 2, 3, 4], 1, fn(acc, x) { acc * x })`, 24},
+		{`reduce(["a", "b", "c"], "", fn(acc, x) { acc + x })`, "abc"},
+		{`reduce(["a", "b"], ">", fn(acc, x) { x + acc })`, "ba>"},
+		{`reduce([], 42, fn(acc, x) { acc + x })`, 42},
+		{`reduce([1, 2, 3], [], fn(acc, x) { push(acc, x * 2) })`, []int{2, 4, 6}},
+		{`reduce([1, 2], 0, fn(acc, x) { let y = x; })`, nil},
+		{`let calls = fn(acc, x) { if (x == 2) { acc + true } else { acc + x } }; reduce([1, 2, 3], 0, calls)`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{`reduce(1, 0, fn(acc, x) { acc })`, errorMessage("first argument to 'reduce' must be an ARRAY, got INTEGER")},
+		{`reduce([1], 0, 1)`, errorMessage("third argument to 'reduce' must be a FUNCTION, got INTEGER")},
+		{`reduce([1], fn(acc, x) { acc })`, errorMessage("wrong number of arguments. got=2, want=3")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestInspectBuiltin tests inspect, and how it differs from str on strings
+func TestInspectBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`inspect("a")`, `"a"`},
+		{`str("a")`, "a"},
+		{`inspect(42)`, "42"},
+		{`inspect(42) == str(42)`, true},
+		{`inspect(true)`, "true"},
+		{`inspect(null)`, "null"},
+		{`inspect([1, 2])`, "[1, 2]"},
+		{`inspect()`, errorMessage("wrong number of arguments. got=0, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestFilledBuiltin tests replacing every element of an array
+func TestFilledBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`filled([1, 2, 3], 0)`, []int{0, 0, 0}},
+		{`filled(["a", "b", "c"], "x")`, []string{"x", "x", "x"}},
+		{`filled([], 0)`, []int{}},
+		{`let a = [1, 2, 3]; filled(a, 0); a`, []int{1, 2, 3}},
+		{`filled("abc", 0)`, errorMessage("first argument to 'filled' must be an ARRAY, got STRING")},
+		{`filled([1])`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestPushAllBuiltin tests appending several elements at once
+func TestPushAllBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`pushAll([1], 2, 3, 4)`, []int{1, 2, 3, 4}},
+		{`pushAll([], 1)`, []int{1}},
+		{`pushAll([1, 2])`, []int{1, 2}},
+		{`let a = [1]; pushAll(a, 2, 3); a`, []int{1}},
+		{`let a = [1]; let b = pushAll(a, 2); let c = pushAll(a, 3); b`, []int{1, 2}},
+		{`pushAll(1, 2)`, errorMessage("first argument to 'pushAll' must be an ARRAY, got INTEGER")},
+		{`pushAll()`, errorMessage("wrong number of arguments. got=0, want=at least 1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// BenchmarkPushLoop builds a 1000 element array by calling push once per element
+func BenchmarkPushLoop(b *testing.B) {
+	parts := benchmarkParts(1000)
+	push := builtins["push"]
+
+	for i := 0; i < b.N; i++ {
+		var arr object.Object = &object.Array{}
+
+		for _, part := range parts {
+			arr = push.Fn(arr, part)
+		}
+	}
+}
+
+// BenchmarkPushAll builds the same array with a single pushAll call
+func BenchmarkPushAll(b *testing.B) {
+	args := append([]object.Object{&object.Array{}}, benchmarkParts(1000)...)
+	pushAll := builtins["pushAll"]
+
+	for i := 0; i < b.N; i++ {
+		pushAll.Fn(args...)
+	}
+}
+
+// TestSliceExpressions tests arr[low:high] slices
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`[10, 20, 30, 40][1:3]`, []int{20, 30}},
+		{`[10, 20, 30, 40][:2]`, []int{10, 20}},
+		{`[10, 20, 30, 40][2:]`, []int{30, 40}},
+		{`[10, 20, 30, 40][:]`, []int{10, 20, 30, 40}},
+		{`[10, 20, 30, 40][-2:]`, []int{30, 40}},
+		{`[10, 20, 30, 40][1:100]`, []int{20, 30, 40}},
+		{`[10, 20, 30, 40][-100:1]`, []int{10}},
+		{`[10, 20, 30, 40][3:1]`, []int{}},
+		{`[][0:1]`, []int{}},
+		{`let a = [1, 2, 3]; let i = 1; a[i:i + 1]`, []int{2}},
+		{`"abc"[0:1]`, errorMessage("slice operator not supported: STRING")},
+		{`[1, 2]["a":]`, errorMessage("slice bounds must be INTEGER, got STRING")},
+		{`[1, 2][:x]`, errorMessage("Identifier not found: x")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestToJsonBuiltin tests encoding values as JSON strings
+func TestToJsonBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`toJson(5)`, "5"},
+		{`toJson([1, "two", true, null])`, `[1,"two",true,null]`},
+		{`toJson({"a": [1.5]})`, `{"a":[1.5]}`},
+		{`toJson({"b": 1, "a": 2})`, `{"b":1,"a":2}`},
+		{`toJson({1: "a", "1": "b"})`, errorMessage(`argument to 'toJson' duplicate JSON key "1"`)},
+		{`toJson({true: 1, "true": 2})`, errorMessage(`argument to 'toJson' duplicate JSON key "true"`)},
+		{`toJson(len)`, errorMessage("argument to 'toJson' BUILTIN can't be converted to JSON")},
+		{`toJson()`, errorMessage("wrong number of arguments. got=0, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestContinueOnError tests that ContinueOnError collects every top-level error of a program, while by default the first one stops it
+func TestContinueOnError(t *testing.T) {
+	defer func() { ContinueOnError = false }()
+
+	input := `let a = 1 + true; let b = 2; puts(c); b * 10`
+
+	testObject(t, testEval(input), errorMessage("type mismatch: INTEGER + BOOLEAN"))
+
+	ContinueOnError = true
+
+	result, ok := testEval(input).(*object.Error)
+	if !ok {
+		t.Fatalf("result is not an Error. got=%T", result)
+	}
+
+	if result.Message != "2 errors" || len(result.Errors) != 2 {
+		t.Fatalf("wrong aggregate error. got=%q with %d errors", result.Message, len(result.Errors))
+	}
+
+	testObject(t, result.Errors[0], errorMessage("type mismatch: INTEGER + BOOLEAN"))
+	testObject(t, result.Errors[1], errorMessage("Identifier not found: c"))
+
+	expected := "ERROR: type mismatch: INTEGER + BOOLEAN\nERROR: Identifier not found: c"
+	if result.Inspect() != expected {
+		t.Errorf("wrong Inspect. expected=%q, got=%q", expected, result.Inspect())
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let b = 2; b * 10`, 20},
+		{`let a = 1 + true; 5`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{`let b = 2; x; b = 3; b`, errorMessage("Identifier not found: x")},
+		{`x; return 5; y`, errorMessage("Identifier not found: x")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestEvalAll tests evaluating a sequence of inputs in one environment, where a parser error doesn't stop the inputs after it
+func TestEvalAll(t *testing.T) {
+	inputs := []string{
+		"let x = 5;",
+		"let = 1;",
+		"let double = fn(n) { n * 2 };",
+		"double(x)",
+		"y",
+	}
+
+	results := EvalAll(inputs, object.NewEnvironment())
+
+	if len(results) != len(inputs) {
+		t.Fatalf("wrong number of results. expected=%d, got=%d", len(inputs), len(results))
+	}
+
+	testNullObject(t, results[0])
+
+	if err, ok := results[1].(*object.Error); !ok || !strings.HasPrefix(err.Message, "parser error(s): ") {
+		t.Errorf("expected a parser error object. got=%s", results[1].Inspect())
+	}
+
+	testNullObject(t, results[2])
+	testIntegerObject(t, results[3], 10)
+	testObject(t, results[4], errorMessage("Identifier not found: y"))
+}
+
+// TestNullOperators tests null with each operator: it only equals itself and everything else is an error
+func TestNullOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"null == null", true},
+		{"null != null", false},
+		{"null == false", false},
+		{"false == null", false},
+		{"null != false", true},
+		{"null == 0", false},
+		{`null == ""`, false},
+		{"null == []", false},
+		{"null != 0", true},
+		{"null + 5", errorMessage("operation on null: NULL + INTEGER")},
+		{"5 + null", errorMessage("operation on null: INTEGER + NULL")},
+		{"null - 1", errorMessage("operation on null: NULL - INTEGER")},
+		{"null * 2.5", errorMessage("operation on null: NULL * FLOAT")},
+		{"1 / null", errorMessage("operation on null: INTEGER / NULL")},
+		{"null % 2", errorMessage("operation on null: NULL % INTEGER")},
+		{`null + "a"`, errorMessage("operation on null: NULL + STRING")},
+		{"null + null", errorMessage("operation on null: NULL + NULL")},
+		{"null < 1", errorMessage("operation on null: NULL < INTEGER")},
+		{"null > null", errorMessage("operation on null: NULL > NULL")},
+		{"null <= 0", errorMessage("operation on null: NULL <= INTEGER")},
+		{"0 >= null", errorMessage("operation on null: INTEGER >= NULL")},
+		{"!null", true},
+		{"!!null", false},
+		{"-null", errorMessage("Illegal prefix operation, expected integer, received: -NULL")},
+		{"null ?? 5", 5},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestKeysAndValues tests listing a hash's keys and values, both in key order
+func TestKeysAndValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len(values({"a": 1, "b": 2, "c": 3}))`, 3},
+		{`values({})`, []int{}},
+		{`values({"b": 2, "a": 1, "c": 3})`, []int{2, 1, 3}},
+		{`values({1: "one", 2: "two"})`, []string{"one", "two"}},
+		{`keys({"b": 2, "a": 1})`, []string{"b", "a"}},
+		{`keys({3: true, 1: false})`, []int{3, 1}},
+		{`keys({"b": 1, "a": 2, "b": 3})`, []string{"b", "a"}},
+		{`keys(with({"b": 1, "a": 2}, "c", 3))`, []string{"b", "a", "c"}},
+		{`keys(with({"b": 1, "a": 2}, "b", 3))`, []string{"b", "a"}},
+		{`values(with({"b": 1, "a": 2}, "b", 3))`, []int{3, 2}},
+		{`keys({k: 1 for k in ["z", "x", "y"]})`, []string{"z", "x", "y"}},
+		{`let h = {"x": 10, "y": 20}; h[keys(h)[1]] == values(h)[1]`, true},
+		{`values([1])`, errorMessage("argument to 'values' must be a HASH, got ARRAY")},
+		{`keys(1)`, errorMessage("argument to 'keys' must be a HASH, got INTEGER")},
+		{`values({}, {})`, errorMessage("wrong number of arguments. got=2, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestMaximumSizes tests that builtins and operators building arrays and strings error past MaxArrayLength and MaxStringLength
+func TestMaximumSizes(t *testing.T) {
+	defer func(arrays, strs int) { MaxArrayLength, MaxStringLength = arrays, strs }(MaxArrayLength, MaxStringLength)
+	MaxArrayLength, MaxStringLength = 3, 4
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`push([1, 2], 3)`, []int{1, 2, 3}},
+		{`push([1, 2, 3], 4)`, errorMessage("array length 4 exceeds maximum size 3")},
+		{`pushAll([1], 2, 3)`, []int{1, 2, 3}},
+		{`pushAll([1], 2, 3, 4)`, errorMessage("array length 4 exceeds maximum size 3")},
+		{`strRepeat("ab", 2)`, "abab"},
+		{`strRepeat("ab", 3)`, errorMessage("string length exceeds maximum size 4")},
+		{`strRepeat("x", 1000000000000)`, errorMessage("string length exceeds maximum size 4")},
+		{`strRepeat("", 1000000000000)`, ""},
+		{`"ab" + "cd"`, "abcd"},
+		{`"ab" + "cde"`, errorMessage("string length exceeds maximum size 4")},
+		{`concatStrings(["ab", "c", "d"])`, "abcd"},
+		{`concatStrings(["ab", "c", "de"])`, errorMessage("string length exceeds maximum size 4")},
+		{`padLeft("a", 4, " ")`, "   a"},
+		{`padRight("a", 5, " ")`, errorMessage("string length exceeds maximum size 4")},
+		{`padLeft("a", 1000000000000, " ")`, errorMessage("string length exceeds maximum size 4")},
+		// Limits count bytes, so two two-byte characters fill the four bytes
+		{`padLeft("é", 2, "é")`, "éé"},
+		{`padLeft("é", 3, "é")`, errorMessage("string length exceeds maximum size 4")},
+		{`flatMap([1, 2], fn(x) { [x, x] })`, errorMessage("array length 4 exceeds maximum size 3")},
+		{`flatMap([1, 2, 3], fn(x) { [x] })`, []int{1, 2, 3}},
+		{`[1, 2, 3]`, []int{1, 2, 3}},
+		{`[1, 2, 3, 4]`, errorMessage("array length 4 exceeds maximum size 3")},
+		{`[x * 2 for x in [1, 2, 3]]`, []int{2, 4, 6}},
+		{`[x for x in {1: 1, 2: 2, 3: 3, 4: 4}]`, errorMessage("array length 4 exceeds maximum size 3")},
+		{`[x for x in {1: 1, 2: 2, 3: 3, 4: 4} if x > 1]`, []int{2, 3, 4}},
+		{`chars("abc")`, []string{"a", "b", "c"}},
+		{`chars("abcd")`, errorMessage("array length 4 exceeds maximum size 3")},
+		{`keys({1: 1, 2: 2, 3: 3, 4: 4})`, errorMessage("array length 4 exceeds maximum size 3")},
+		{`values({1: 1, 2: 2, 3: 3, 4: 4})`, errorMessage("array length 4 exceeds maximum size 3")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestFunctionName tests that a function literal bound by let is named after its binding
+func TestFunctionName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let fact = fn(n) { n }; fact", "fn fact(n) {\nn\n}"},
+		{"let fact = fn(n) { n }; let f = fact; f", "fn fact(n) {\nn\n}"},
+		{"let f = fn(n) { n } in f", "fn f(n) {\nn\n}"},
+		{"fn(n) { n }", "fn(n) {\nn\n}"},
+		{"let make = fn() { fn(n) { n } }; let g = make(); g", "fn(n) {\nn\n}"},
+	}
+
+	for _, tt := range tests {
+		fn, ok := testEval(tt.input).(*object.Function)
+		if !ok {
+			t.Errorf("%q didn't evaluate to a function", tt.input)
+			continue
+		}
+
+		if fn.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect for %q. expected=%q, got=%q", tt.input, tt.expected, fn.Inspect())
+		}
+	}
+}
+
+// TestChainedIndexExpressions tests indexing the result of a call or of another index
+func TestChainedIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let getArray = fn() { [1, 2, 3] }; getArray()[2]", 3},
+		{"let matrix = [[1, 2], [3, 4]]; matrix[1][0]", 3},
+		{`let h = {"a": {"b": "c"}}; h["a"]["b"]`, "c"},
+		{"let fs = fn() { [fn(x) { x * 2 }] }; fs()[0](4)", 8},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestCallMethod tests calling a function stored in a hash with the hash as its first argument
+func TestCallMethod(t *testing.T) {
+	counter := `let counter = {
+		"count": 0,
+		"increment": fn(self, by) { with(self, "count", self["count"] + by) },
+		"get": fn(self) { self["count"] }
+	};`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{counter + `callMethod(counter, "get")`, 0},
+		{counter + `let c = callMethod(counter, "increment", 2); callMethod(c, "get")`, 2},
+		{counter + `let c = callMethod(callMethod(counter, "increment", 2), "increment", 3); c["count"]`, 5},
+		{counter + `callMethod(counter, "increment", 2); counter["count"]`, 0},
+		{counter + `callMethod(counter, "increment")(4)["count"]`, 4},
+		{`let dog = {"name": "Buck", "describe": fn(self, greeting) { greeting + ", " + self["name"] }}; callMethod(dog, "describe", "Hello")`, "Hello, Buck"},
+		{`let obj = {"fields": keys}; callMethod(obj, "fields")`, []string{"fields"}},
+		{counter + `callMethod(counter, "reset")`, errorMessage("hash has no method: reset")},
+		{counter + `callMethod(counter, "count")`, errorMessage("method 'count' must be a FUNCTION, got INTEGER")},
+		{`callMethod([1], "get")`, errorMessage("first argument to 'callMethod' must be a HASH, got ARRAY")},
+		{`callMethod({}, 1)`, errorMessage("second argument to 'callMethod' must be a STRING, got INTEGER")},
+		{`callMethod({})`, errorMessage("wrong number of arguments. got=1, want=at least 2")},
+		{counter + `callMethod(counter, "get", 1)`, errorMessage("wrong number of arguments. got=2, want=1")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestSort tests sorting arrays by value and with a comparator, without changing the input
+func TestSort(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"sort([3, 1, 2])", []int{1, 2, 3}},
+		{"sort([5, -1, 5, 0])", []int{-1, 0, 5, 5}},
+		{"sort([])", []int{}},
+		{`sort(["pear", "apple", "fig"])`, []string{"apple", "fig", "pear"}},
+		{`sort(["b", "B", "a"])`, []string{"B", "a", "b"}},
+		{"sort([2.5, 1, 2])[0]", 1},
+		{"sort([2.5, 1, 2])[2]", 2.5},
+		{"sort([9223372036854775807, 9223372036854775806])[0]", 9223372036854775806},
+		{"let a = [3, 1, 2]; sort(a); a", []int{3, 1, 2}},
+		{"sort([1, 2, 3], fn(a, b) { b - a })", []int{3, 2, 1}},
+		{`sort(["ccc", "a", "bb"], fn(a, b) { len(a) - len(b) })`, []string{"a", "bb", "ccc"}},
+		{`[x[1] for x in sort([[2, "b"], [1, "x"], [2, "a"]], fn(a, b) { a[0] - b[0] })]`, []string{"x", "b", "a"}},
+		{`sort([1, "a"])`, errorMessage("can't sort an array mixing INTEGER and STRING")},
+		{`sort(["a", 1.5])`, errorMessage("can't sort an array mixing STRING and FLOAT")},
+		{"sort([[1], [0]])", errorMessage("can't sort elements of type ARRAY without a comparator")},
+		{"sort([2, 1], fn(a, b) { a < b })", errorMessage("comparator for 'sort' must return an INTEGER, got BOOLEAN")},
+		{"sort([2, 1], fn(a, b) { a + true })", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"sort([2, 1], 1)", errorMessage("second argument to 'sort' must be a FUNCTION, got INTEGER")},
+		{"sort(1)", errorMessage("first argument to 'sort' must be an ARRAY, got INTEGER")},
+		{"sort()", errorMessage("wrong number of arguments. got=0, want=1 or 2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestTernaryExpression tests that cond ? a : b evaluates only the branch the condition chooses
+func TestTernaryExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`(5 > 3) ? "yes" : "no"`, "yes"},
+		{`5 < 3 ? "yes" : "no"`, "no"},
+		{`null ? 1 : 2`, 2},
+		{`0 ? 1 : 2`, 1},
+		{`let x = 7; x % 2 == 0 ? "even" : x > 5 ? "big odd" : "small odd"`, "big odd"},
+		{`let max = fn(a, b) { a > b ? a : b }; max(3, 9)`, 9},
+		{`true ? 1 : 1 + true`, 1},
+		{`false ? 1 + true : 2`, 2},
+		{`let n = 0; let bump = fn() { n = n + 1 }; true ? 0 : bump(); false ? bump() : 0; n`, 0},
+		{`y ? 1 : 2`, errorMessage("Identifier not found: y")},
+		{`true ? 1 + true : 2`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestHashMethodCalls tests calling functions fetched from hashes, for method-like dispatch
+func TestHashMethodCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let obj = {"greet": fn() { "hi" }}; obj["greet"]()`, "hi"},
+		{`let obj = {"double": fn(x) { x * 2 }}; obj["double"](4) + 1`, 9},
+		{`let obj = {"inner": {"add": fn(a, b) { a + b }}}; obj["inner"]["add"](2, 3)`, 5},
+		{`let shapes = {"square": fn(x) { x * x }, "cube": fn(x) { x * x * x }}; let area = fn(kind, x) { shapes[kind](x) }; area("cube", 2)`, 8},
+		{`{"f": fn() { 7 }}["f"]()`, 7},
+		{`let obj = {"n": 1}; obj["n"]()`, errorMessage("Not a function, received type: INTEGER")},
+		{`let obj = {}; obj["missing"]()`, errorMessage("Not a function, received type: NULL")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestArrayComprehension tests building arrays with comprehensions
+func TestArrayComprehension(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[x * 2 for x in [1, 2, 3]]", []int{2, 4, 6}},
+		{"[x for x in [1, 2, 3, 4] if x % 2 == 0]", []int{2, 4}},
+		{"[x for x in []]", []int{}},
+		{"[x for x in [1, 2] if false]", []int{}},
+		{`[k for k in {"b": 1, "a": 2}]`, []string{"b", "a"}},
+		{"let n = 10; [x + n for x in [1, 2]]", []int{11, 12}},
+		{"let x = 5; [x for x in [1, 2]]; x", 5},
+		{"[x + true for x in [1]]", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"[x for x in [1] if y]", errorMessage("Identifier not found: y")},
+		{"[x for x in 5]", errorMessage("comprehension collection must be an ARRAY or HASH, got INTEGER")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestStringEscapes tests that escapes in string literals evaluate to the characters they stand for
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"line1\nline2"`, "line1\nline2"},
+		{`"a\tb"`, "a\tb"},
+		{`"a\rb"`, "a\rb"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`len("\n\t")`, 2},
+		{`inspect("say \"hi\"")`, `"say \"hi\""`},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestHashComprehension tests building hashes with comprehensions
+func TestHashComprehension(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let h = {x: x * x for x in [0, 1, 2]}; [len(keys(h)), h[0], h[1], h[2]]", []int{3, 0, 1, 4}},
+		{`let h = {s: len(s) for s in ["a", "bcd"] if s != "a"}; [len(keys(h)), h["bcd"]]`, []int{1, 3}},
+		{"len(keys({1: x for x in [1, 2, 3]}))", 1},
+		{"{1: x for x in [1, 2, 3]}[1]", 3},
+		{"len(keys({x: 1 for x in []}))", 0},
+		{"{[x]: x for x in [1]}", errorMessage("Unusable as hash key: ARRAY")},
+		{"{x: x + true for x in [1]}", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"{x: x for x in 1}", errorMessage("comprehension collection must be an ARRAY or HASH, got INTEGER")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestUnicodeSource tests Unicode identifiers and strings, and that len counts characters rather than bytes
+func TestUnicodeSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let café = 5; café * 2", 10},
+		{`let 名前 = "世界"; 名前`, "世界"},
+		{`"party " + "🎉"`, "party 🎉"},
+		{`len("héllo")`, 5},
+		{`len("🎉🎉")`, 2},
+		{`len("日本語")`, 3},
+		{`len("hello")`, 5},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestBareWords tests that unbound identifiers are errors by default and strings of their name with BareWords on
+func TestBareWords(t *testing.T) {
+	testObject(t, testEval("let team = Broncos; team"), errorMessage("Identifier not found: Broncos"))
+
+	BareWords = true
+	defer func() { BareWords = false }()
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let team = Broncos; team", "Broncos"},
+		{`let team = Broncos; team == "Broncos"`, true},
+		{"let Broncos = 1; let team = Broncos; team", 1},
+		{"x = 1", errorMessage("identifier not found: x")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+
+	// Builtins still win over bare words
+	if _, ok := testEval("len").(*object.Builtin); !ok {
+		t.Errorf("len didn't evaluate to the builtin with BareWords on")
+	}
+}
+
+// TestSplit tests splitting strings at a separator
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`split("a,b,c", ",")`, []string{"a", "b", "c"}},
+		{`split("a, b", ", ")`, []string{"a", "b"}},
+		{`split("abc", "")`, []string{"a", "b", "c"}},
+		{`split("héllo", "")`, []string{"h", "é", "l", "l", "o"}},
+		{`split("abc", ";")`, []string{"abc"}},
+		{`split("", ",")`, []string{""}},
+		{`split("", "")`, []string{}},
+		{`split(",a,", ",")`, []string{"", "a", ""}},
+		{`split(1, ",")`, errorMessage("first argument to 'split' must be a STRING, got INTEGER")},
+		{`split("a", 1)`, errorMessage("second argument to 'split' must be a STRING, got INTEGER")},
+		{`split("a")`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestLargeLengthArguments tests that counts and bounds too big for a 32-bit int are clamped rather than wrapped around
+func TestLargeLengthArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"take([1, 2, 3], 4294967297)", []int{1, 2, 3}},
+		{"drop([1, 2, 3], 4294967297)", []int{}},
+		{"take([1, 2, 3], -4294967297)", []int{}},
+		{"slice([1, 2, 3], 4294967297)", []int{}},
+		{"slice([1, 2, 3], 0, 4294967297)", []int{1, 2, 3}},
+		{`substr("abc", -4294967297)`, "abc"},
+		{"[1, 2, 3][0:4294967297]", []int{1, 2, 3}},
+		{"[1, 2, 3][4294967296]", nil},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestStringIndexExpressions tests that indexing a string returns the character at that position, or null out of range
+func TestStringIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"hello"[0]`, "h"},
+		{`"hello"[4]`, "o"},
+		{`let s = "hello"; s[len(s) - 1]`, "o"},
+		{`"hello"[5]`, nil},
+		{`"hello"[-1]`, nil},
+		{`""[0]`, nil},
+		{`"héllo"[1]`, "é"},
+		{`"🎉!"[1]`, "!"},
+		{`"abc"[5, "z"]`, "z"},
+		{`"abc"[1, "z"]`, "b"},
+		{`"abc"["a"]`, errorMessage("Index operator not supported: STRING")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestShortCircuitPredicates tests find, every and some, and that they stop calling the predicate once the result is known
+func TestShortCircuitPredicates(t *testing.T) {
+	// Each call counts the elements its predicate was tested on, and evaluates to [result, calls]
+	counted := []struct {
+		call     string
+		expected string
+	}{
+		{"find([1, 2, 3, 4], fn(x) { calls = calls + 1; x > 1 })", "[2, 2]"},
+		{"every([1, 2, 3, 4], fn(x) { calls = calls + 1; x < 2 })", "[false, 2]"},
+		{"some([1, 2, 3, 4], fn(x) { calls = calls + 1; x == 3 })", "[true, 3]"},
+		{"find([1, 2], fn(x) { calls = calls + 1; false })", "[null, 2]"},
+		{"every([1, 2], fn(x) { calls = calls + 1; true })", "[true, 2]"},
+		{"some([1, 2], fn(x) { calls = calls + 1; false })", "[false, 2]"},
+	}
+
+	for _, tt := range counted {
+		evaluated := testEval("let calls = 0; let result = " + tt.call + "; [result, calls]")
+
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong [result, calls] for %q. expected=%s, got=%s", tt.call, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"every([], fn(x) { false })", true},
+		{"some([], fn(x) { true })", false},
+		{"find([], fn(x) { true })", nil},
+		{"find([1, 2], fn(x) { if (x == 2) { x + true } })", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"find([1, 2], fn(x) { if (x == 1) { true } else { x + true } })", 1},
+		{"some(1, fn(x) { x })", errorMessage("first argument to 'some' must be an ARRAY, got INTEGER")},
+		{"every([1], 1)", errorMessage("second argument to 'every' must be a FUNCTION, got INTEGER")},
+		{"find([1])", errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestContains tests searching strings for substrings and arrays for elements
+func TestContains(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`contains("Doorkey", "key")`, true},
+		{`contains("Doorkey", "lock")`, false},
+		{`contains("Doorkey", "")`, true},
+		{`contains("", "a")`, false},
+		{`contains([1, 2, 3], 2)`, true},
+		{`contains([1, 2, 3], 4)`, false},
+		{`contains(["a", "b"], "b")`, true},
+		{`contains([true], false)`, false},
+		{`contains([1, null], null)`, true},
+		{`contains([1.0], 1)`, true},
+		{`contains(["1"], 1)`, false},
+		{`contains([], 1)`, false},
+		{`contains("abc", 1)`, errorMessage("second argument to 'contains' must be a STRING for a STRING, got INTEGER")},
+		{`contains(1, 1)`, errorMessage("first argument to 'contains' must be a STRING or ARRAY, got INTEGER")},
+		{`contains([1])`, errorMessage("wrong number of arguments. got=1, want=2")},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+// TestStructuralEquality tests that == and != compare arrays and hashes by their contents
+func TestStructuralEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, 2] == [2, 1]", false},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`[1] == {"a": 1}`, false},
+		{"let f = fn(x) { x }; f == f", true},
+		{"contains([[1, 2], [3]], [3])", true},
+		{`contains([{"a": 1}], {"a": 1})`, true},
+	}
+
+	for _, tt := range tests {
+		testObject(t, testEval(tt.input), tt.expected)
+	}
+}
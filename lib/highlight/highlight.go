@@ -0,0 +1,33 @@
+/*
+Syntax highlighting for
+Doorkey, a Monkey Derivative
+by Travis Moore
+By following "Writing an Interpreter in Go" by Thorsten Ball, https://interpreterbook.com/
+*/
+
+// Package highlight splits Doorkey source into typed spans so an editor can color it
+package highlight
+
+import (
+	"github.com/tmoore2016/interpreter/lib/lexer"
+	"github.com/tmoore2016/interpreter/lib/token"
+)
+
+// Span is the byte range of one token in the source, source[Start:End], and its type
+type Span struct {
+	Type  token.TokenType
+	Start int
+	End   int
+}
+
+// Spans lexes the source, keeping comments, and returns a span for each token in order. Whitespace between tokens isn't covered by any span.
+func Spans(source string) []Span {
+	l := lexer.NewWithComments(source)
+	spans := []Span{}
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		spans = append(spans, Span{Type: tok.Type, Start: tok.Start, End: tok.End})
+	}
+
+	return spans
+}
@@ -0,0 +1,55 @@
+/*
+Syntax highlighting tests for
+Doorkey, a Monkey Derivative
+by Travis Moore
+By following "Writing an Interpreter in Go" by Thorsten Ball, https://interpreterbook.com/
+*/
+
+package highlight
+
+import (
+	"testing"
+
+	"github.com/tmoore2016/interpreter/lib/token"
+)
+
+// TestSpans tests the spans for a short program with a keyword, a string, a number and a comment
+func TestSpans(t *testing.T) {
+	source := `let s = "hi"; // greet
+5`
+
+	expected := []struct {
+		tokenType token.TokenType
+		text      string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "s"},
+		{token.ASSIGN, "="},
+		{token.STRING, `"hi"`},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "// greet"},
+		{token.INT, "5"},
+	}
+
+	spans := Spans(source)
+
+	if len(spans) != len(expected) {
+		t.Fatalf("wrong number of spans. expected=%d, got=%d (%v)", len(expected), len(spans), spans)
+	}
+
+	for i, tt := range expected {
+		span := spans[i]
+
+		if span.Type != tt.tokenType {
+			t.Errorf("spans[%d] - type wrong. expected=%q, got=%q", i, tt.tokenType, span.Type)
+		}
+
+		if text := source[span.Start:span.End]; text != tt.text {
+			t.Errorf("spans[%d] - text wrong. expected=%q, got=%q", i, tt.text, text)
+		}
+	}
+
+	if spans[3].Start != 8 || spans[3].End != 12 {
+		t.Errorf("string span wrong. expected=8-12, got=%d-%d", spans[3].Start, spans[3].End)
+	}
+}
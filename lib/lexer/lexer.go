@@ -7,60 +7,140 @@ By following "Writing an Interpreter in Go" by Thorsten Ball, https://interprete
 
 // lexer/lexer.go
 
-// Exercise: Support Unicode, "So it's left as an exercise to the reader to fully support Unicode (and emojis!) in Monkey."
+// Input is read as UTF-8, so identifiers can use any Unicode letters and strings can hold any characters, emoji included.
 
 package lexer
 
-import "github.com/tmoore2016/interpreter/lib/token"
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tmoore2016/interpreter/lib/token"
+)
 
 // Lexer for input and pointers
 type Lexer struct {
 	input        string
-	position     int  // current lexer position (points to current ch)
-	readPosition int  // current reading position in input (after current ch). Enables Peek?
-	ch           byte // current char being examined
+	position     int  // current lexer position in bytes (points to current ch)
+	readPosition int  // current reading position in input in bytes (after current ch). Enables Peek?
+	ch           rune // current char being examined
+	line         int  // line of the current char, from 1
+	column       int  // column of the current char, from 1
+	keepComments bool // return // comments as COMMENT tokens instead of skipping them
+
+	// TabWidth is how many columns a tab advances the column by, so reported columns can match an editor's display. New sets it to 1, one column per char.
+	TabWidth int
 }
 
 // New calls *Lexer's readChar before NextToken is called and initializes pointers
 func New(input string) *Lexer { // Call new input, prepare Lexer
-	l := &Lexer{input: input} // Create Lexer instance with input
-	l.readChar()              // Initialize Lexer pointer
-	return l                  // when all input is lexed
+	l := &Lexer{input: input, line: 1, TabWidth: 1} // Create Lexer instance with input
+	l.readChar()                                    // Initialize Lexer pointer
+	return l                                        // when all input is lexed
+}
+
+// NewWithComments creates a Lexer that returns each // comment as a COMMENT token, for doc tooling. The token's literal is the comment text without the // and surrounding spaces.
+func NewWithComments(input string) *Lexer {
+	l := New(input)
+	l.keepComments = true
+	return l
 }
 
-// readChar reads each char in the input string. The read pointer's position is always one ahead of the Lexer pointer's position, unless there are 0 chars left
+// readChar reads each char in the input string. The read pointer's position is always one char ahead of the Lexer pointer's position, unless there are 0 chars left
+// Chars are UTF-8 runes, so positions move by the rune's width in bytes. Decoding costs more than indexing a byte, so ASCII, the common case, is read directly and only other runes are decoded.
 func (l *Lexer) readChar() {
 
+	// Track the position of the char being read, a newline moves the next one to the start of the following line and a tab moves it TabWidth columns on
+	if l.ch == '\n' {
+		l.line++
+		l.column = 1
+	} else if l.ch == '\t' && l.TabWidth > 0 {
+		l.column += l.TabWidth
+	} else {
+		l.column++
+	}
+
+	width := 1
+
 	if l.readPosition >= len(l.input) { // If greater than 0, Lexer's read position keeps incrementing until it is beyond input length.
 		l.ch = 0 // Lexer char is 0, nil?.
 
+	} else if b := l.input[l.readPosition]; b < utf8.RuneSelf {
+		l.ch = rune(b) // lexer char is lexer's read position from input
 	} else {
-		l.ch = l.input[l.readPosition] // lexer char is lexer's read position from input
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 
 	l.position = l.readPosition // Lexer's char position advances to lexer's read position
-	l.readPosition++            // Lexer's read pointer advances to the next input char
+	l.readPosition += width     // Lexer's read pointer advances to the next input char
 }
 
 // peekChar returns the next char in the input string (the read char), but doesn't increment the position
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
+	return l.peekCharAt(1)
+}
 
-	if l.readPosition >= len(l.input) { // If Lexer's read position is beyond the input length
-		return 0 // No peek char
+// peekCharAt returns the char offset chars ahead of the current char without advancing, peekCharAt(1) is the next char. It returns 0 past the end of the input. Operators that share a prefix, like / and //, can look further ahead than peekChar to tell them apart.
+func (l *Lexer) peekCharAt(offset int) rune {
+	if offset < 0 {
+		return 0
+	}
 
-	} else {
-		return l.input[l.readPosition] // Send the lexer's read position to the lexer as input
+	// Step over offset chars, each as wide as its UTF-8 encoding
+	index := l.position
+	for i := 0; i < offset && index < len(l.input); i++ {
+		_, width := utf8.DecodeRuneInString(l.input[index:])
+		index += width
 	}
+
+	if index >= len(l.input) { // If the index is outside the input
+		return 0 // No peek char
+	}
+
+	ch, _ := utf8.DecodeRuneInString(l.input[index:])
+
+	return ch
 }
 
-// NextToken looks to see which is called
+// NextToken looks to see which is called, and records the line and column the token starts at and the byte offsets of its source text
 // Could be a Loop that calls a text file
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
-
 	// Initialize skipping whitespace
 	l.skipWhitespace()
 
+	line, column, start := l.line, l.column, l.position
+
+	tok := l.readToken()
+	tok.Line, tok.Column = line, column
+
+	// The lexer stops on the char after the token, which is past the end of the input after the last one
+	tok.Start, tok.End = start, l.position
+	if tok.End > len(l.input) {
+		tok.End = len(l.input)
+	}
+	if tok.Start > len(l.input) {
+		tok.Start = len(l.input)
+	}
+
+	return tok
+}
+
+// Tokens reads the rest of the input and returns its tokens, not including the final EOF
+func (l *Lexer) Tokens() []token.Token {
+	tokens := []token.Token{}
+
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		tokens = append(tokens, tok)
+	}
+
+	return tokens
+}
+
+// readToken reads the token starting at the current char
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
+
 	// this can be generalized
 	// Lexer's char determines the token type
 	switch l.ch {
@@ -88,6 +168,26 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.NOT, l.ch)
 		}
+	// '??' or the '?' of a ternary
+	case '?':
+		if l.peekChar() == '?' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.COALESCE, Literal: literal}
+		} else {
+			tok = newToken(token.QUESTION, l.ch)
+		}
+	// '|>', a lone '|' is illegal
+	case '|':
+		if l.peekChar() == '>' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.PIPE, Literal: literal}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -102,14 +202,39 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.PLUS, l.ch)
 	case '-':
 		tok = newToken(token.MINUS, l.ch)
+	// '/', or '//' when comments are kept
 	case '/':
+		if l.peekChar() == '/' {
+			tok.Type = token.COMMENT
+			tok.Literal = l.readComment()
+			return tok
+		}
+
 		tok = newToken(token.DIVIDE, l.ch)
 	case '*':
 		tok = newToken(token.MULTIPLY, l.ch)
+	case '%':
+		tok = newToken(token.MODULO, l.ch)
+	// '<' or '<='
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.LTE, Literal: literal}
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
+	// '>' or '>='
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.GTE, Literal: literal}
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
 	case '{':
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
@@ -119,8 +244,12 @@ func (l *Lexer) NextToken() token.Token {
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
 	case '"':
+		var ok bool
 		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		tok.Literal, ok = l.readString()
+		if !ok {
+			tok.Type = token.ILLEGAL
+		}
 	//case '':
 	//	tok = newToken(token.ASSIGN, )
 
@@ -138,8 +267,7 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Literal = l.readNumber()
-			tok.Type = token.INT
+			tok.Literal, tok.Type = l.readNumber()
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -150,13 +278,36 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
-// skipWhitespace ignores white space
+// skipWhitespace ignores white space and // comments
 func (l *Lexer) skipWhitespace() {
-	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' { // \r = return
-		l.readChar() // Advance lexer pointers
+	for {
+		for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' { // \r = return
+			l.readChar() // Advance lexer pointers
+		}
+
+		if l.keepComments || l.ch != '/' || l.peekChar() != '/' {
+			return
+		}
+
+		l.skipComment()
+	}
+}
+
+// skipComment advances the lexer past a // comment, up to the next newline or EOF
+func (l *Lexer) skipComment() {
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
 	}
 }
 
+// readComment reads a // comment up to the next newline or EOF and returns its text without the // and surrounding spaces
+func (l *Lexer) readComment() string {
+	position := l.position
+	l.skipComment()
+
+	return strings.TrimSpace(l.input[position+2 : l.position])
+}
+
 /*
 Advance lexer for each type, could be generalized with a loop
 */
@@ -170,47 +321,91 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position] // Send lexer new position input
 }
 
-// advances the lexer's position until it encounters a non-number char
-func (l *Lexer) readNumber() string {
+// advances the lexer's position until it encounters a non-number char. A single '.' followed by a digit makes the number a float.
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	position := l.position // match indexes
+	tokenType := token.TokenType(token.INT)
 	// for
 	for isDigit(l.ch) { // for each lexer position that is a digit,
 		l.readChar() // advance
 	}
-	return l.input[position:l.position] // Send lexer new position input
+
+	// 3.14, but not 3. or 3.foo
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+
+		for isDigit(l.ch) {
+			l.readChar()
+		}
+	}
+
+	return l.input[position:l.position], tokenType // Send lexer new position input
 }
 
-// Advances the lexer until it encounters a closing " or EOF. Previous characters are part of a string.
-// Add error reporting and character escaping ("hello \"world\"")
-func (l *Lexer) readString() string {
-	position := l.position + 1
+// readString advances the lexer to the closing " and returns the string's value, translating the escapes \", \n, \t, \r and \\.
+// ok is false for an unterminated string or an unknown escape, and the string's source text is returned instead for the ILLEGAL token.
+func (l *Lexer) readString() (value string, ok bool) {
+	start := l.position
+	ok = true
+
+	var out strings.Builder
+
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+
+		switch l.ch {
+		case '"':
+			if !ok {
+				return l.input[start : l.position+1], false
+			}
+			return out.String(), true
+
+		case 0:
+			return l.input[start:l.position], false
+
+		case '\\':
+			l.readChar()
+
+			switch l.ch {
+			case '"', '\\':
+				out.WriteRune(l.ch)
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case 'r':
+				out.WriteByte('\r')
+			case 0:
+				return l.input[start:l.position], false
+			default:
+				// Keep reading to the closing " so lexing carries on after the string
+				ok = false
+			}
+
+		default:
+			out.WriteRune(l.ch)
 		}
 	}
-	return l.input[position:l.position]
 }
 
 /*
 Booleans for token types
 */
 
-// returns true if token is 1 byte string, _ and $ are letters for var names
-// Too many ors?
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch == '$'
+// returns true if the char is a Unicode letter, _ and $ are letters for var names too
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_' || ch == '$'
 }
 
-// returns true if character is a digit, 0-9
-func isDigit(ch byte) bool {
+// returns true if character is a digit, 0-9. Other Unicode digits aren't part of numbers.
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
 // returns true if character is one-character token
 
-// initialize the tokens, they are 1 byte Type string
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+// initialize the tokens, they are 1 char Type string
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
@@ -38,6 +38,12 @@ func TestNextToken(t *testing.T) {
 		"new string"
 		[1, 2];
 		{"The Sea Wolf": "Jack London"}
+		null ?? 5;
+		x |> f;
+		10 % 3;
+		a <= b >= c < d > e;
+		[x for x in a];
+		a ? b : c;
 	`
 
 	// A collection of tests
@@ -166,6 +172,54 @@ func TestNextToken(t *testing.T) {
 		{token.STRING, "Jack London"},
 		{token.RBRACE, "}"},
 
+		// null ?? 5;
+		{token.NULL, "null"},
+		{token.COALESCE, "??"},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+
+		// x |> f;
+		{token.IDENT, "x"},
+		{token.PIPE, "|>"},
+		{token.IDENT, "f"},
+		{token.SEMICOLON, ";"},
+
+		// 10 % 3;
+		{token.INT, "10"},
+		{token.MODULO, "%"},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
+
+		// a <= b >= c < d > e;
+		{token.IDENT, "a"},
+		{token.LTE, "<="},
+		{token.IDENT, "b"},
+		{token.GTE, ">="},
+		{token.IDENT, "c"},
+		{token.LT, "<"},
+		{token.IDENT, "d"},
+		{token.GT, ">"},
+		{token.IDENT, "e"},
+		{token.SEMICOLON, ";"},
+
+		// [x for x in a];
+		{token.LBRACKET, "["},
+		{token.IDENT, "x"},
+		{token.FOR, "for"},
+		{token.IDENT, "x"},
+		{token.IN, "in"},
+		{token.IDENT, "a"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+
+		// a ? b : c;
+		{token.IDENT, "a"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "b"},
+		{token.COLON, ":"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+
 		// description
 		// {token., },
 
@@ -189,3 +243,372 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+// TestComments tests that // comments are skipped to the end of the line, while a lone / is still division
+func TestComments(t *testing.T) {
+	input := `// a whole line comment
+let x = 10 / 2; // set x
+// two comment lines
+// in a row
+x // trailing comment with no newline`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "10"},
+		{token.DIVIDE, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+// TestFloatTokens tests that a '.' followed by digits makes a float, and any other '.' doesn't
+func TestFloatTokens(t *testing.T) {
+	input := `3.14 0.5 10 7.`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "0.5"},
+		{token.INT, "10"},
+		{token.INT, "7"},
+		{token.ILLEGAL, "."},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+// TestPeekCharAt tests looking ahead more than one char without advancing the lexer
+func TestPeekCharAt(t *testing.T) {
+	l := New("a<=b")
+
+	tests := []struct {
+		offset   int
+		expected rune
+	}{
+		{0, 'a'},
+		{1, '<'},
+		{2, '='},
+		{3, 'b'},
+		{4, 0},
+		{100, 0},
+		{-1, 0},
+	}
+
+	for _, tt := range tests {
+		if got := l.peekCharAt(tt.offset); got != tt.expected {
+			t.Errorf("peekCharAt(%d) wrong. expected=%q, got=%q", tt.offset, tt.expected, got)
+		}
+	}
+
+	if l.peekChar() != l.peekCharAt(1) {
+		t.Errorf("peekChar() and peekCharAt(1) differ. got=%q and %q", l.peekChar(), l.peekCharAt(1))
+	}
+
+	// Peeking doesn't move the lexer
+	if tok := l.NextToken(); tok.Type != token.IDENT || tok.Literal != "a" {
+		t.Errorf("lexer advanced while peeking. got=%q %q", tok.Type, tok.Literal)
+	}
+}
+
+// TestTwoCharLookahead tests tokens that need to see two chars past the current one: at "<" the lexer must see "=" to choose LTE, and at the last digit of a number it must see "." followed by a digit to read a float
+func TestTwoCharLookahead(t *testing.T) {
+	input := `1<=2 3.5 4.x 5//6`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "1"},
+		{token.LTE, "<="},
+		{token.INT, "2"},
+		{token.FLOAT, "3.5"},
+		{token.INT, "4"},
+		{token.ILLEGAL, "."},
+		{token.IDENT, "x"},
+		{token.INT, "5"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+// TestKeepComments tests that a lexer made with NewWithComments returns comments as tokens
+func TestKeepComments(t *testing.T) {
+	input := `//   adds numbers
+let x = 10 / 2; // trailing
+x`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.COMMENT, "adds numbers"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "10"},
+		{token.DIVIDE, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "trailing"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	l := NewWithComments(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+// TestStringEscapes tests that escapes in string literals are translated, and that unknown escapes and unterminated strings are illegal
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{`"say \"hi\""`, token.STRING, `say "hi"`},
+		{`"line1\nline2"`, token.STRING, "line1\nline2"},
+		{`"a\tb"`, token.STRING, "a\tb"},
+		{`"a\rb"`, token.STRING, "a\rb"},
+		{`"back\\slash"`, token.STRING, `back\slash`},
+		{`"\\n"`, token.STRING, `\n`},
+		{`"plain"`, token.STRING, "plain"},
+		{`"bad \q escape"`, token.ILLEGAL, `"bad \q escape"`},
+		{`"unterminated`, token.ILLEGAL, `"unterminated`},
+		{`"ends in \`, token.ILLEGAL, `"ends in \`},
+	}
+
+	for _, tt := range tests {
+		tok := New(tt.input).NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Errorf("%s - tokentype wrong. expected=%q, got=%q", tt.input, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("%s - literal wrong. expected=%q, got=%q", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	// Lexing carries on after a string with an unknown escape
+	l := New(`"\q" + 1`)
+	l.NextToken()
+
+	if tok := l.NextToken(); tok.Type != token.PLUS {
+		t.Errorf("lexing didn't resume after the illegal string. got=%q %q", tok.Type, tok.Literal)
+	}
+}
+
+// TestTokenPositions tests that tokens record the line and column they start at
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x == \"a b\" // note\n\n10"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"x", 2, 3},
+		{"==", 2, 5},
+		{"a b", 2, 8},
+		{"10", 4, 1},
+		{"", 4, 3},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - %q position wrong. expected=%d:%d, got=%d:%d", i, tok.Literal, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+// TestTabWidth tests that a tab advances the column by TabWidth, one column by default
+func TestTabWidth(t *testing.T) {
+	input := "let x = 1;\n\tx\t+ 2"
+
+	tests := []struct {
+		tabWidth int
+		expected []int // columns of x, + and 2 on the second line
+	}{
+		{1, []int{2, 4, 6}},
+		{4, []int{5, 10, 12}},
+		{8, []int{9, 18, 20}},
+	}
+
+	for _, tt := range tests {
+		l := New(input)
+		l.TabWidth = tt.tabWidth
+
+		for l.NextToken().Type != token.SEMICOLON {
+		}
+
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+
+			if tok.Line != 2 || tok.Column != expected {
+				t.Errorf("TabWidth %d: %q position wrong. expected=2:%d, got=%d:%d", tt.tabWidth, tok.Literal, expected, tok.Line, tok.Column)
+			}
+
+			if i == 0 && tok.Literal != "x" {
+				t.Fatalf("TabWidth %d: expected x first on line 2. got=%q", tt.tabWidth, tok.Literal)
+			}
+		}
+	}
+}
+
+// TestUnicode tests that identifiers can use Unicode letters, strings can hold multi-byte characters, and columns count characters rather than bytes
+func TestUnicode(t *testing.T) {
+	input := `let café = "héllo 🎉"; 日本 + ü`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "café", 5},
+		{token.ASSIGN, "=", 10},
+		{token.STRING, "héllo 🎉", 12},
+		{token.SEMICOLON, ";", 21},
+		{token.IDENT, "日本", 23},
+		{token.PLUS, "+", 26},
+		{token.IDENT, "ü", 28},
+		{token.EOF, "", 29},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+
+		if tok.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - %q column wrong. expected=%d, got=%d", i, tok.Literal, tt.expectedColumn, tok.Column)
+		}
+	}
+
+	// Peeking steps over whole characters
+	l = New("é🎉x")
+	if got := l.peekCharAt(2); got != 'x' {
+		t.Errorf("peekCharAt(2) wrong. expected=%q, got=%q", 'x', got)
+	}
+
+	// A character that isn't a letter is still illegal
+	if tok := New("→").NextToken(); tok.Type != token.ILLEGAL || tok.Literal != "→" {
+		t.Errorf("expected an ILLEGAL → token. got=%q %q", tok.Type, tok.Literal)
+	}
+}
+
+// TestTokenOffsets tests that each token's byte offsets cover its source text
+func TestTokenOffsets(t *testing.T) {
+	input := `let é = "a\"b"; // hi
+x`
+
+	expected := []string{"let", "é", "=", `"a\"b"`, ";", "// hi", "x", ""}
+
+	l := NewWithComments(input)
+
+	for i, want := range expected {
+		tok := l.NextToken()
+
+		if got := input[tok.Start:tok.End]; got != want {
+			t.Errorf("tests[%d] - source text wrong. expected=%q, got=%q", i, want, got)
+		}
+	}
+}
+
+// TestTokens tests reading all of the remaining tokens at once
+func TestTokens(t *testing.T) {
+	tokens := New("let x = 5;").Tokens()
+
+	expected := []token.TokenType{token.LET, token.IDENT, token.ASSIGN, token.INT, token.SEMICOLON}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("wrong number of tokens. expected=%d, got=%d", len(expected), len(tokens))
+	}
+
+	for i, tokenType := range expected {
+		if tokens[i].Type != tokenType {
+			t.Errorf("tokens[%d] - tokentype wrong. expected=%q, got=%q", i, tokenType, tokens[i].Type)
+		}
+	}
+
+	if tokens := New("").Tokens(); len(tokens) != 0 {
+		t.Errorf("empty input gave tokens. got=%v", tokens)
+	}
+}
@@ -9,6 +9,13 @@ By following "Writing an Interpreter in Go" by Thorsten Ball, https://interprete
 
 package object
 
+import (
+	"bufio"
+	"io"
+	"os"
+	"sort"
+)
+
 // NewEnvironment creates a hash table (map) that associates strings with object, like a let statement name with its value.
 func NewEnvironment() *Environment {
 
@@ -19,8 +26,15 @@ func NewEnvironment() *Environment {
 
 // Environment structure is a hash table that associates a string (name) with an object. The outer environment allows one environment to wrap another.
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store  map[string]Object
+	consts map[string]bool // names in store bound by const
+	outer  *Environment
+	input  *bufio.Reader // where readLine reads, see SetIO
+	output io.Writer     // where puts writes, see SetIO
+
+	ioBuiltins map[string]*Builtin // builtins made for this environment's input and output, see IOBuiltin
+
+	topLevelReturn *bool // whether a program may return at the top level, see SetTopLevelReturn
 }
 
 // Get returns an object if the name is associated with an environment (map)
@@ -38,10 +52,78 @@ func (e *Environment) Get(name string) (Object, bool) {
 func (e *Environment) Set(name string, val Object) Object {
 
 	e.store[name] = val
+	delete(e.consts, name)
 
 	return val
 }
 
+// SetConst associates a name with an object and marks it constant, so Assign won't change it
+func (e *Environment) SetConst(name string, val Object) Object {
+	if e.consts == nil {
+		e.consts = make(map[string]bool)
+	}
+
+	e.store[name] = val
+	e.consts[name] = true
+
+	return val
+}
+
+// IsConst reports whether name is bound by const in this environment, and with includeOuter whether the nearest binding of name in any environment is
+func (e *Environment) IsConst(name string, includeOuter bool) bool {
+	if _, ok := e.store[name]; ok || !includeOuter || e.outer == nil {
+		return e.consts[name]
+	}
+
+	return e.outer.IsConst(name, true)
+}
+
+// Assign updates an existing binding, in this environment or the nearest outer one that has the name. It returns false without binding anything if the name isn't bound anywhere or its binding is constant.
+func (e *Environment) Assign(name string, val Object) (Object, bool) {
+	if _, ok := e.store[name]; ok {
+		if e.consts[name] {
+			return nil, false
+		}
+
+		e.store[name] = val
+		return val, true
+	}
+
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+
+	return nil, false
+}
+
+// Names returns the names bound in this environment, not its outer ones, in sorted order
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.store))
+
+	for name := range e.store {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// All returns a copy of the bindings in this environment, and with includeOuter those of the outer environments too, where an inner binding shadows an outer one. Changing the map doesn't change the environment.
+func (e *Environment) All(includeOuter bool) map[string]Object {
+	bindings := make(map[string]Object, len(e.store))
+
+	if includeOuter && e.outer != nil {
+		bindings = e.outer.All(true)
+	}
+
+	for name, val := range e.store {
+		bindings[name] = val
+	}
+
+	return bindings
+}
+
 // NewEnclosedEnvironment allows one environment to wrap another.
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
@@ -49,3 +131,76 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 
 	return env
 }
+
+// SetIO sets where readLine reads and puts writes for code evaluated in this environment and the environments it encloses. Passing a *bufio.Reader shares its buffered input instead of wrapping it again.
+func (e *Environment) SetIO(in io.Reader, out io.Writer) {
+	e.input = bufio.NewReader(in)
+	e.output = out
+}
+
+// Input returns the reader set by SetIO on the nearest environment that has one, or standard input
+func (e *Environment) Input() *bufio.Reader {
+	if e.input != nil {
+		return e.input
+	}
+
+	if e.outer != nil {
+		return e.outer.Input()
+	}
+
+	// Kept on the outermost environment, so later reads don't lose what an earlier one buffered
+	e.input = bufio.NewReader(os.Stdin)
+
+	return e.input
+}
+
+// IOBuiltin returns the builtin with the given name that uses the input and output of this environment, making it with makeBuiltin the first time. It's kept on the environment that SetIO was called on, or the outermost one, so every lookup that shares the input and output gets the same builtin.
+func (e *Environment) IOBuiltin(name string, makeBuiltin func(env *Environment) *Builtin) *Builtin {
+	if e.input == nil && e.output == nil && e.outer != nil {
+		return e.outer.IOBuiltin(name, makeBuiltin)
+	}
+
+	if builtin, ok := e.ioBuiltins[name]; ok {
+		return builtin
+	}
+
+	if e.ioBuiltins == nil {
+		e.ioBuiltins = make(map[string]*Builtin)
+	}
+
+	builtin := makeBuiltin(e)
+	e.ioBuiltins[name] = builtin
+
+	return builtin
+}
+
+// Output returns the writer set by SetIO on the nearest environment that has one, or standard output
+func (e *Environment) Output() io.Writer {
+	if e.output != nil {
+		return e.output
+	}
+
+	if e.outer != nil {
+		return e.outer.Output()
+	}
+
+	return os.Stdout
+}
+
+// SetTopLevelReturn sets whether a program evaluated in this environment may use return outside of a function, in place of the evaluator's TopLevelReturn default
+func (e *Environment) SetTopLevelReturn(allowed bool) {
+	e.topLevelReturn = &allowed
+}
+
+// TopLevelReturn returns the setting from SetTopLevelReturn on the nearest environment that has one, or def when none does
+func (e *Environment) TopLevelReturn(def bool) bool {
+	if e.topLevelReturn != nil {
+		return *e.topLevelReturn
+	}
+
+	if e.outer != nil {
+		return e.outer.TopLevelReturn(def)
+	}
+
+	return def
+}
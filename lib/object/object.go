@@ -11,9 +11,14 @@ package object
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/tmoore2016/interpreter/lib/ast"
 )
@@ -27,6 +32,7 @@ type BuiltinFunction func(args ...Object) Object
 // Strings for Doorkey data types
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	STRING_OBJ       = "STRING"
 	ARRAY_OBJ        = "ARRAY"
 	BOOLEAN_OBJ      = "BOOLEAN"
@@ -38,6 +44,21 @@ const (
 	HASH_OBJ         = "HASH"
 )
 
+// friendlyNames are the user-facing names of types that don't read well lowercased
+var friendlyNames = map[ObjectType]string{
+	RETURN_VALUE_OBJ: "return value",
+	BUILTIN_OBJ:      "builtin function",
+}
+
+// FriendlyName returns a lowercase name for an ObjectType for use in error messages, INTEGER becomes "integer". The ObjectType constants are still used for dispatch.
+func FriendlyName(t ObjectType) string {
+	if name, ok := friendlyNames[t]; ok {
+		return name
+	}
+
+	return strings.ToLower(string(t))
+}
+
 // Object represents each data type with a type and value
 type Object interface {
 	Type() ObjectType
@@ -59,9 +80,33 @@ func (i *Integer) Type() ObjectType {
 	return INTEGER_OBJ
 }
 
+// Float type object.Float
+type Float struct {
+	Value float64
+}
+
+// Inspect Float returns the shortest decimal that represents the value, always with a decimal point so 4.0 isn't mistaken for an integer
+func (f *Float) Inspect() string {
+	out := strconv.FormatFloat(f.Value, 'f', -1, 64)
+
+	if !strings.Contains(out, ".") && !math.IsInf(f.Value, 0) && !math.IsNaN(f.Value) {
+		out += ".0"
+	}
+
+	return out
+}
+
+// Type Float ObjectType
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
 // String type object.String
 type String struct {
 	Value string
+
+	hashKey HashKey // cached by HashKey(), strings are immutable so the key never changes
+	hashed  bool
 }
 
 // Type string ObjectType
@@ -74,6 +119,25 @@ func (s *String) Inspect() string {
 	return s.Value
 }
 
+// internPool holds one shared *String per interned value. Almost every call finds its string already there, which a sync.Map serves without locking.
+var internPool sync.Map
+
+// Intern returns the shared String for a value, creating it on first use. Strings are never mutated, so equal interned strings can be one instance: they compare equal by pointer and their hash key is only computed once. The pool is never emptied, so only intern values that repeat.
+func Intern(value string) *String {
+	if s, ok := internPool.Load(value); ok {
+		return s.(*String)
+	}
+
+	// Compute the cached hash key before other goroutines can get the string, so HashKey never writes to a shared String
+	s := &String{Value: value}
+	s.HashKey()
+
+	// If another goroutine interned the value first, use its string so there's still only one
+	shared, _ := internPool.LoadOrStore(value, s)
+
+	return shared.(*String)
+}
+
 // Array structure for an array object
 type Array struct {
 	Elements []Object
@@ -146,7 +210,9 @@ func (rv *ReturnValue) Inspect() string {
 
 // Function object structure
 type Function struct {
+	Name       string // The name a let statement bound the function literal to, empty for anonymous functions
 	Parameters []*ast.Identifier
+	Defaults   map[string]ast.Expression // Default values by parameter name, evaluated at call time
 	Body       *ast.BlockStatement
 	Env        *Environment // A pointer to the particular environment
 }
@@ -165,11 +231,14 @@ func (f *Function) Inspect() string {
 
 	for _, p := range f.Parameters {
 
-		params = append(params, p.String())
+		params = append(params, ast.ParameterString(p, f.Defaults))
 	}
 
 	// Adds the function notation, parameters, and function body to the object
 	out.WriteString("fn")
+	if f.Name != "" {
+		out.WriteString(" " + f.Name)
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
@@ -193,6 +262,8 @@ func (b *Builtin) Inspect() string { return "builtin function" }
 // Error structure for error message objects
 type Error struct {
 	Message string
+	Trace   []string // names of the functions the error propagated out of, innermost first
+	Errors  []*Error // when the error aggregates several, like a program's top-level errors with evaluator.ContinueOnError, each of them in order
 }
 
 // Type of object: ERROR_OBJ
@@ -200,13 +271,32 @@ func (e *Error) Type() ObjectType {
 	return ERROR_OBJ
 }
 
-// Inspect Error returns error message (ERROR_OBJ value)
+// Inspect Error returns error message (ERROR_OBJ value), followed by a line for each function call it propagated out of. An aggregate error lists each of its errors instead.
 func (e *Error) Inspect() string {
-	return "ERROR: " + e.Message
+	var out bytes.Buffer
+
+	if len(e.Errors) != 0 {
+		for i, err := range e.Errors {
+			if i > 0 {
+				out.WriteString("\n")
+			}
+			out.WriteString(err.Inspect())
+		}
+
+		return out.String()
+	}
+
+	out.WriteString("ERROR: " + e.Message)
+
+	for _, frame := range e.Trace {
+		out.WriteString("\n  in " + frame)
+	}
+
+	return out.String()
 }
 
 // HashKey structure for hash keys. Type is any object type, value is an integer.
-// Caching HashKey methods would be a performance optimization
+// String keys are cached after the first call, Integer and Boolean keys are cheap enough to build each time.
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
@@ -225,17 +315,29 @@ func (b *Boolean) HashKey() HashKey {
 	return HashKey{Type: b.Type(), Value: value}
 }
 
+// HashKey function for null, every null has the same fixed key
+func (n *Null) HashKey() HashKey {
+	return HashKey{Type: n.Type(), Value: 0}
+}
+
 // HashKey function for comparing integer values
 func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
-// HashKey function for comparing string values
+// HashKey function for comparing string values, the FNV hash is computed once and cached on the string. The cache isn't locked, so only a String that isn't shared yet, or whose key is already cached as Intern's are, is safe to use across goroutines.
 func (s *String) HashKey() HashKey {
+	if s.hashed {
+		return s.hashKey
+	}
+
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
 
-	return HashKey{Type: s.Type(), Value: h.Sum64()}
+	s.hashKey = HashKey{Type: s.Type(), Value: h.Sum64()}
+	s.hashed = true
+
+	return s.hashKey
 }
 
 // HashPair structure contains the objects that generated the HashKey, their type and values.
@@ -244,9 +346,52 @@ type HashPair struct {
 	Value Object
 }
 
+// SortedHashInspect makes Hash.Inspect list pairs sorted by key instead of in insertion order
+var SortedHashInspect = false
+
 // Hash structure points to the HashKey and the HashPair
 type Hash struct {
 	Pairs map[HashKey]HashPair
+	order []HashKey // keys in the order Set first added them
+}
+
+// Set adds or replaces a pair. A new key goes after the existing ones in OrderedPairs, replacing a value keeps the key's place.
+func (h *Hash) Set(hashKey HashKey, pair HashPair) {
+	if h.Pairs == nil {
+		h.Pairs = make(map[HashKey]HashPair)
+	}
+
+	if _, ok := h.Pairs[hashKey]; !ok {
+		h.order = append(h.order, hashKey)
+	}
+
+	h.Pairs[hashKey] = pair
+}
+
+// OrderedPairs returns the hash's pairs in the order their keys were added with Set. Pairs written straight into Pairs have no recorded order, so they come last, sorted by key.
+func (h *Hash) OrderedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	seen := make(map[HashKey]bool, len(h.Pairs))
+
+	for _, hashKey := range h.order {
+		if pair, ok := h.Pairs[hashKey]; ok && !seen[hashKey] {
+			pairs = append(pairs, pair)
+			seen[hashKey] = true
+		}
+	}
+
+	if len(pairs) == len(h.Pairs) {
+		return pairs
+	}
+
+	unordered := &Hash{Pairs: make(map[HashKey]HashPair)}
+	for hashKey, pair := range h.Pairs {
+		if !seen[hashKey] {
+			unordered.Pairs[hashKey] = pair
+		}
+	}
+
+	return append(pairs, unordered.SortedPairs()...)
 }
 
 // Type returns HASH_OBJ type
@@ -258,9 +403,15 @@ func (h *Hash) Type() ObjectType {
 func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
+	hashPairs := h.OrderedPairs()
+
+	if SortedHashInspect {
+		hashPairs = h.SortedPairs()
+	}
+
 	pairs := []string{}
 
-	for _, pair := range h.Pairs {
+	for _, pair := range hashPairs {
 		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 
@@ -271,7 +422,212 @@ func (h *Hash) Inspect() string {
 	return out.String()
 }
 
+// SortedPairs returns the hash's pairs sorted by key, so code walking a hash gets the same order every time
+func (h *Hash) SortedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return keyLess(pairs[i].Key, pairs[j].Key)
+	})
+
+	return pairs
+}
+
+// keyLess orders hash keys for SortedHashInspect. Keys of different types are ordered by type name, integers numerically, strings lexicographically, and false before true.
+func keyLess(a, b Object) bool {
+	if a.Type() != b.Type() {
+		return a.Type() < b.Type()
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value < b.(*Integer).Value
+	case *String:
+		return a.Value < b.(*String).Value
+	case *Boolean:
+		return !a.Value && b.(*Boolean).Value
+	default:
+		return false
+	}
+}
+
 // Hashable determines whether the type given is suitable for hashing.
 type Hashable interface {
 	HashKey() HashKey
 }
+
+// Equals reports whether two values are equal. Numbers compare by value, so 1 equals 1.0, strings, booleans and null by value, and arrays and hashes by their contents, recursively.
+// Anything else, like a function, is only equal to itself.
+func Equals(a, b Object) bool {
+	switch a := a.(type) {
+	case *Integer:
+		switch b := b.(type) {
+		case *Integer:
+			return a.Value == b.Value
+		case *Float:
+			return float64(a.Value) == b.Value
+		}
+		return false
+
+	case *Float:
+		switch b := b.(type) {
+		case *Float:
+			return a.Value == b.Value
+		case *Integer:
+			return a.Value == float64(b.Value)
+		}
+		return false
+
+	case *String:
+		b, ok := b.(*String)
+		return ok && a.Value == b.Value
+
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+
+		for i := range a.Elements {
+			if !Equals(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || len(a.Pairs) != len(b.Pairs) {
+			return false
+		}
+
+		for key, pair := range a.Pairs {
+			other, ok := b.Pairs[key]
+			if !ok || !Equals(pair.Value, other.Value) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return a == b
+	}
+}
+
+// ToJSON encodes a Doorkey value as JSON. Hash pairs are written in insertion order, keys that aren't strings use their Inspect form, and two keys with the same form are an error. Functions, builtins and errors can't be encoded.
+func ToJSON(obj Object) (string, error) {
+	value, err := jsonValue(obj)
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(encoded), nil
+}
+
+// jsonObject is a JSON object that keeps its members in order, where encoding/json would sort the keys of a map
+type jsonObject []jsonMember
+
+// jsonMember is one key and value of a jsonObject
+type jsonMember struct {
+	key   string
+	value interface{}
+}
+
+// MarshalJSON encodes the members in order
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+
+	out.WriteString("{")
+
+	for i, member := range o {
+		if i > 0 {
+			out.WriteString(",")
+		}
+
+		key, err := json.Marshal(member.key)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(member.value)
+		if err != nil {
+			return nil, err
+		}
+
+		out.Write(key)
+		out.WriteString(":")
+		out.Write(value)
+	}
+
+	out.WriteString("}")
+
+	return out.Bytes(), nil
+}
+
+// jsonValue converts a Doorkey value into the Go value encoding/json marshals it from
+func jsonValue(obj Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return obj.Value, nil
+	case *Float:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *Null:
+		return nil, nil
+	case *Array:
+		elements := make([]interface{}, len(obj.Elements))
+		for i, el := range obj.Elements {
+			value, err := jsonValue(el)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = value
+		}
+		return elements, nil
+	case *Hash:
+		members := make(jsonObject, 0, len(obj.Pairs))
+		seen := make(map[string]bool, len(obj.Pairs))
+
+		for _, pair := range obj.OrderedPairs() {
+			key := pair.Key.Inspect()
+			if str, ok := pair.Key.(*String); ok {
+				key = str.Value
+			}
+
+			// JSON keys are strings, so 1 and "1" would become the same key and one value would be lost
+			if seen[key] {
+				return nil, fmt.Errorf("duplicate JSON key %q", key)
+			}
+			seen[key] = true
+
+			value, err := jsonValue(pair.Value)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, jsonMember{key: key, value: value})
+		}
+		return members, nil
+	default:
+		return nil, fmt.Errorf("%s can't be converted to JSON", obj.Type())
+	}
+}
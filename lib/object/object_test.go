@@ -9,7 +9,12 @@ By following "Writing an Interpreter in Go" by Thorsten Ball, https://interprete
 
 package object
 
-import "testing"
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+)
 
 // TestStringHashKey tests diffs of hash keys of strings, identical values should have the same hash keys.
 func TestStringHashKey(t *testing.T) {
@@ -31,6 +36,31 @@ func TestStringHashKey(t *testing.T) {
 	}
 }
 
+// TestStringHashKeyCache tests that a cached string hash key matches a freshly computed one
+func TestStringHashKeyCache(t *testing.T) {
+	cached := &String{Value: "The Call of the Wild"}
+	first := cached.HashKey()
+	second := cached.HashKey()
+
+	if first != second {
+		t.Errorf("Repeated HashKey calls returned different keys. first=%+v, second=%+v", first, second)
+	}
+
+	fresh := &String{Value: "The Call of the Wild"}
+	if fresh.HashKey() != second {
+		t.Errorf("Cached hash key doesn't match a fresh hash key. cached=%+v, fresh=%+v", second, fresh.HashKey())
+	}
+
+	empty := &String{}
+	if empty.HashKey() != (&String{Value: ""}).HashKey() {
+		t.Errorf("Empty strings have different hash keys.")
+	}
+
+	if empty.HashKey() == cached.HashKey() {
+		t.Errorf("Strings with different content have the same hash keys.")
+	}
+}
+
 // TestIntHashKey tests diffs of hash keys with integer values, identical values should have the same hash keys.
 func TestIntHashKey(t *testing.T) {
 	index1 := &Integer{Value: 001}
@@ -70,3 +100,463 @@ func TestBooleanHashKey(t *testing.T) {
 		t.Errorf("Booleans of different values have the same hash keys.")
 	}
 }
+
+// BenchmarkStringHashKeyCached measures repeated HashKey calls on the same string, as in a loop indexing a hash
+func BenchmarkStringHashKeyCached(b *testing.B) {
+	key := &String{Value: "a reasonably long key used to index a hash in a tight loop"}
+
+	for i := 0; i < b.N; i++ {
+		key.HashKey()
+	}
+}
+
+// BenchmarkStringHashKeyUncached measures HashKey calls on a new string each time, which always runs the FNV hash
+func BenchmarkStringHashKeyUncached(b *testing.B) {
+	value := "a reasonably long key used to index a hash in a tight loop"
+
+	for i := 0; i < b.N; i++ {
+		key := &String{Value: value}
+		key.HashKey()
+	}
+}
+
+// TestNullHashKey tests that every null shares a hash key that doesn't collide with other zero values
+func TestNullHashKey(t *testing.T) {
+	null1 := &Null{}
+	null2 := &Null{}
+
+	if null1.HashKey() != null2.HashKey() {
+		t.Errorf("Nulls have different hash keys.")
+	}
+
+	if null1.HashKey() == (&Integer{Value: 0}).HashKey() {
+		t.Errorf("Null has the same hash key as 0.")
+	}
+
+	if null1.HashKey() == (&Boolean{Value: false}).HashKey() {
+		t.Errorf("Null has the same hash key as false.")
+	}
+}
+
+// TestFloatInspect tests that floats always print with a decimal point
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{3.5, "3.5"},
+		{4, "4.0"},
+		{-0.25, "-0.25"},
+		{1e21, "1000000000000000000000.0"},
+		{math.Inf(1), "+Inf"},
+	}
+
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+
+		if f.Inspect() != tt.expected {
+			t.Errorf("Float.Inspect() wrong. expected=%q, got=%q", tt.expected, f.Inspect())
+		}
+	}
+}
+
+// TestFriendlyName tests the lowercase user-facing type names
+func TestFriendlyName(t *testing.T) {
+	tests := []struct {
+		objectType ObjectType
+		expected   string
+	}{
+		{INTEGER_OBJ, "integer"},
+		{FLOAT_OBJ, "float"},
+		{STRING_OBJ, "string"},
+		{BOOLEAN_OBJ, "boolean"},
+		{ARRAY_OBJ, "array"},
+		{HASH_OBJ, "hash"},
+		{NULL_OBJ, "null"},
+		{FUNCTION_OBJ, "function"},
+		{BUILTIN_OBJ, "builtin function"},
+		{RETURN_VALUE_OBJ, "return value"},
+	}
+
+	for _, tt := range tests {
+		if got := FriendlyName(tt.objectType); got != tt.expected {
+			t.Errorf("FriendlyName(%s) wrong. expected=%q, got=%q", tt.objectType, tt.expected, got)
+		}
+	}
+}
+
+// TestIntern tests that interned strings with equal values are the same instance
+func TestIntern(t *testing.T) {
+	first := Intern("The Sea-Wolf")
+	second := Intern("The Sea-" + "Wolf")
+	other := Intern("White Fang")
+
+	if first != second {
+		t.Errorf("Interned strings with the same value are different instances.")
+	}
+
+	if first == other {
+		t.Errorf("Interned strings with different values are the same instance.")
+	}
+
+	if first.Value != "The Sea-Wolf" || other.Value != "White Fang" {
+		t.Errorf("Interned strings have the wrong values. got=%q and %q", first.Value, other.Value)
+	}
+
+	if first.HashKey() != (&String{Value: "The Sea-Wolf"}).HashKey() {
+		t.Errorf("Interned string has a different hash key than an equal string.")
+	}
+}
+
+// TestInternConcurrentHashKey tests that goroutines sharing an interned string can all call HashKey. Run with -race to check nothing writes to the shared String.
+func TestInternConcurrentHashKey(t *testing.T) {
+	expected := (&String{Value: "shared"}).HashKey()
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	keys := make([]HashKey, 8)
+
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			keys[i] = Intern("shared").HashKey()
+		}(i)
+	}
+
+	// Release the goroutines together so they race for the first Intern
+	close(start)
+	wg.Wait()
+
+	for i, key := range keys {
+		if key != expected {
+			t.Errorf("goroutine %d got a different hash key. got=%+v, want=%+v", i, key, expected)
+		}
+	}
+}
+
+// BenchmarkIntern measures looking up an already interned string
+func BenchmarkIntern(b *testing.B) {
+	Intern("interned key")
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		benchmarkSink = Intern("interned key")
+		benchmarkSink.HashKey()
+	}
+}
+
+// benchmarkSink keeps benchmarked strings reachable, so they're allocated on the heap like evaluated strings are
+var benchmarkSink *String
+
+// BenchmarkNoIntern measures allocating and hashing a new string each time
+func BenchmarkNoIntern(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		benchmarkSink = &String{Value: "interned key"}
+		benchmarkSink.HashKey()
+	}
+}
+
+// TestSortedHashInspect tests that SortedHashInspect lists mixed keys by type name, then by value
+func TestSortedHashInspect(t *testing.T) {
+	SortedHashInspect = true
+	defer func() { SortedHashInspect = false }()
+
+	keys := []Object{
+		&String{Value: "b"},
+		&Integer{Value: 10},
+		&Boolean{Value: true},
+		&String{Value: "a"},
+		&Integer{Value: -2},
+		&Boolean{Value: false},
+		&Integer{Value: 3},
+	}
+
+	hash := &Hash{Pairs: make(map[HashKey]HashPair)}
+
+	for i, key := range keys {
+		hash.Pairs[key.(Hashable).HashKey()] = HashPair{Key: key, Value: &Integer{Value: int64(i)}}
+	}
+
+	expected := "{false: 5, true: 2, -2: 4, 3: 6, 10: 1, a: 3, b: 0}"
+
+	// Map iteration order is random, so check more than once
+	for i := 0; i < 10; i++ {
+		if hash.Inspect() != expected {
+			t.Fatalf("wrong sorted Inspect. got=%q, want=%q", hash.Inspect(), expected)
+		}
+	}
+}
+
+// TestEnvironmentAssign tests that Assign updates the nearest existing binding and doesn't create new ones
+func TestEnvironmentAssign(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+
+	inner := NewEnclosedEnvironment(outer)
+
+	if _, ok := inner.Assign("x", &Integer{Value: 2}); !ok {
+		t.Fatalf("Assign didn't find x in the outer environment")
+	}
+
+	if x, _ := outer.Get("x"); x.(*Integer).Value != 2 {
+		t.Errorf("outer x wasn't updated. got=%s", x.Inspect())
+	}
+
+	inner.Set("x", &Integer{Value: 3})
+	inner.Assign("x", &Integer{Value: 4})
+
+	if x, _ := outer.Get("x"); x.(*Integer).Value != 2 {
+		t.Errorf("outer x was updated through a shadowing binding. got=%s", x.Inspect())
+	}
+
+	if _, ok := inner.Assign("y", &Integer{Value: 1}); ok {
+		t.Errorf("Assign bound an unbound name")
+	}
+
+	if _, ok := inner.Get("y"); ok {
+		t.Errorf("Assign created a binding for an unbound name")
+	}
+}
+
+// TestEnvironmentConst tests that a const binding can't be assigned, but can be shadowed in an inner environment
+func TestEnvironmentConst(t *testing.T) {
+	outer := NewEnvironment()
+	outer.SetConst("PI", &Integer{Value: 3})
+
+	inner := NewEnclosedEnvironment(outer)
+
+	if !outer.IsConst("PI", false) || !inner.IsConst("PI", true) {
+		t.Errorf("PI isn't reported as constant")
+	}
+
+	if inner.IsConst("PI", false) {
+		t.Errorf("PI is reported as constant in an environment that doesn't bind it")
+	}
+
+	if _, ok := inner.Assign("PI", &Integer{Value: 4}); ok {
+		t.Errorf("Assign changed a constant")
+	}
+
+	if pi, _ := outer.Get("PI"); pi.(*Integer).Value != 3 {
+		t.Errorf("PI was changed. got=%s", pi.Inspect())
+	}
+
+	inner.Set("PI", &Integer{Value: 4})
+
+	if inner.IsConst("PI", true) {
+		t.Errorf("a let binding shadowing a constant is reported as constant")
+	}
+
+	if _, ok := inner.Assign("PI", &Integer{Value: 5}); !ok {
+		t.Errorf("Assign didn't change the shadowing binding")
+	}
+}
+
+// TestToJSON tests encoding Doorkey values as JSON
+func TestToJSON(t *testing.T) {
+	hash := &Hash{Pairs: map[HashKey]HashPair{}}
+	for _, key := range []Hashable{&String{Value: "a"}, &Integer{Value: 2}} {
+		hash.Pairs[key.HashKey()] = HashPair{Key: key.(Object), Value: &Boolean{Value: true}}
+	}
+
+	tests := []struct {
+		input    Object
+		expected string
+	}{
+		{&Integer{Value: 5}, "5"},
+		{&Float{Value: 2.5}, "2.5"},
+		{&String{Value: "say \"hi\""}, `"say \"hi\""`},
+		{&Null{}, "null"},
+		{&Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "x"}, &Null{}}}, `[1,"x",null]`},
+		{hash, `{"2":true,"a":true}`},
+	}
+
+	for _, tt := range tests {
+		got, err := ToJSON(tt.input)
+		if err != nil {
+			t.Errorf("ToJSON(%s) returned an error: %s", tt.input.Inspect(), err)
+			continue
+		}
+
+		if got != tt.expected {
+			t.Errorf("wrong JSON for %s. expected=%s, got=%s", tt.input.Inspect(), tt.expected, got)
+		}
+	}
+
+	if _, err := ToJSON(&Builtin{}); err == nil {
+		t.Errorf("ToJSON of a builtin didn't return an error")
+	}
+
+	// Keys set with Set are encoded in insertion order
+	ordered := &Hash{}
+	for _, key := range []*String{{Value: "b"}, {Value: "a"}, {Value: "c"}} {
+		ordered.Set(key.HashKey(), HashPair{Key: key, Value: &Integer{Value: 1}})
+	}
+
+	if got, _ := ToJSON(ordered); got != `{"b":1,"a":1,"c":1}` {
+		t.Errorf("hash not encoded in insertion order. got=%s", got)
+	}
+
+	// Keys that encode to the same JSON string are an error rather than a lost value
+	collisions := [][]Hashable{
+		{&Integer{Value: 1}, &String{Value: "1"}},
+		{&Boolean{Value: true}, &String{Value: "true"}},
+	}
+
+	for _, keys := range collisions {
+		hash := &Hash{}
+		for i, key := range keys {
+			hash.Set(key.HashKey(), HashPair{Key: key.(Object), Value: &Integer{Value: int64(i)}})
+		}
+
+		_, err := ToJSON(hash)
+		expected := fmt.Sprintf("duplicate JSON key %q", keys[1].(*String).Value)
+
+		if err == nil || err.Error() != expected {
+			t.Errorf("wrong error for %s. expected=%q, got=%v", hash.Inspect(), expected, err)
+		}
+	}
+}
+
+// TestEnvironmentNames tests listing the names bound in an environment
+func TestEnvironmentNames(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("z", &Integer{Value: 1})
+
+	env := NewEnclosedEnvironment(outer)
+	env.Set("b", &Integer{Value: 2})
+	env.Set("a", &Integer{Value: 3})
+
+	names := env.Names()
+	if len(names) != 2 || names[0] != "a" || names[1] != "b" {
+		t.Errorf("wrong names. expected=[a b], got=%v", names)
+	}
+}
+
+// TestEnvironmentAll tests copying an environment's bindings, with and without its outer environments
+func TestEnvironmentAll(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+	outer.Set("y", &Integer{Value: 2})
+
+	env := NewEnclosedEnvironment(outer)
+	env.Set("x", &Integer{Value: 3})
+
+	local := env.All(false)
+	if len(local) != 1 || local["x"].(*Integer).Value != 3 {
+		t.Errorf("wrong local bindings. got=%v", local)
+	}
+
+	all := env.All(true)
+	if len(all) != 2 || all["x"].(*Integer).Value != 3 || all["y"].(*Integer).Value != 2 {
+		t.Errorf("wrong bindings including outer. got=%v", all)
+	}
+
+	all["z"] = &Integer{Value: 4}
+	delete(all, "y")
+
+	if _, ok := env.Get("z"); ok {
+		t.Errorf("adding to the copy bound z in the environment")
+	}
+
+	if _, ok := outer.Get("y"); !ok {
+		t.Errorf("deleting from the copy removed y from the outer environment")
+	}
+}
+
+// TestEquals tests value equality across types, including nested arrays and hashes
+func TestEquals(t *testing.T) {
+	hash := func(pairs ...Object) *Hash {
+		h := &Hash{Pairs: map[HashKey]HashPair{}}
+		for i := 0; i < len(pairs); i += 2 {
+			h.Pairs[pairs[i].(Hashable).HashKey()] = HashPair{Key: pairs[i], Value: pairs[i+1]}
+		}
+		return h
+	}
+	array := func(elements ...Object) *Array { return &Array{Elements: elements} }
+	integer := func(n int64) *Integer { return &Integer{Value: n} }
+	str := func(s string) *String { return &String{Value: s} }
+
+	fn := &Function{}
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{integer(1), integer(1), true},
+		{integer(1), integer(2), false},
+		{integer(1), &Float{Value: 1}, true},
+		{&Float{Value: 1.5}, integer(1), false},
+		{&Float{Value: 2.5}, &Float{Value: 2.5}, true},
+		{str("a"), str("a"), true},
+		{str("a"), str("b"), false},
+		{str("1"), integer(1), false},
+		{&Boolean{Value: true}, &Boolean{Value: true}, true},
+		{&Boolean{Value: false}, &Null{}, false},
+		{&Null{}, &Null{}, true},
+		{array(), array(), true},
+		{array(integer(1), str("a")), array(integer(1), str("a")), true},
+		{array(integer(1)), array(integer(1), integer(2)), false},
+		{array(array(integer(1)), array()), array(array(integer(1)), array()), true},
+		{array(array(integer(1))), array(array(integer(2))), false},
+		{hash(str("a"), integer(1)), hash(str("a"), integer(1)), true},
+		{hash(str("a"), integer(1), integer(2), str("b")), hash(integer(2), str("b"), str("a"), integer(1)), true},
+		{hash(str("a"), integer(1)), hash(str("a"), integer(2)), false},
+		{hash(str("a"), integer(1)), hash(str("b"), integer(1)), false},
+		{hash(str("a"), integer(1)), hash(), false},
+		{hash(str("a"), array(hash(str("b"), integer(1)))), hash(str("a"), array(hash(str("b"), integer(1)))), true},
+		{array(), hash(), false},
+		{fn, fn, true},
+		{fn, &Function{}, false},
+	}
+
+	for _, tt := range tests {
+		if got := Equals(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Equals(%s, %s) = %t, want=%t", tt.a.Inspect(), tt.b.Inspect(), got, tt.expected)
+		}
+	}
+}
+
+// TestHashOrderedPairs tests that OrderedPairs follows the order keys were first set, with unordered pairs last in key order
+func TestHashOrderedPairs(t *testing.T) {
+	set := func(h *Hash, key string, value int64) {
+		k := &String{Value: key}
+		h.Set(k.HashKey(), HashPair{Key: k, Value: &Integer{Value: value}})
+	}
+
+	hash := &Hash{}
+	set(hash, "c", 1)
+	set(hash, "a", 2)
+	set(hash, "b", 3)
+	set(hash, "a", 4)
+
+	unordered := &String{Value: "0"}
+	hash.Pairs[unordered.HashKey()] = HashPair{Key: unordered, Value: &Integer{Value: 5}}
+
+	expected := []struct {
+		key   string
+		value int64
+	}{{"c", 1}, {"a", 4}, {"b", 3}, {"0", 5}}
+
+	pairs := hash.OrderedPairs()
+	if len(pairs) != len(expected) {
+		t.Fatalf("OrderedPairs returned %d pairs, want=%d", len(pairs), len(expected))
+	}
+
+	for i, want := range expected {
+		key := pairs[i].Key.(*String).Value
+		value := pairs[i].Value.(*Integer).Value
+
+		if key != want.key || value != want.value {
+			t.Errorf("pairs[%d] = %s: %d, want=%s: %d", i, key, value, want.key, want.value)
+		}
+	}
+
+	if got := hash.Inspect(); got != "{c: 1, a: 4, b: 3, 0: 5}" {
+		t.Errorf("Inspect() = %s", got)
+	}
+}
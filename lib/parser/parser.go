@@ -8,8 +8,10 @@ By following "Writing an Interpreter in Go" by Thorsten Ball, https://interprete
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/tmoore2016/interpreter/lib/ast"
 	"github.com/tmoore2016/interpreter/lib/lexer"
@@ -20,8 +22,12 @@ import (
 const (
 	_           int = iota // iota assigns values in ascending order
 	LOWEST                 // lowest precedence
+	ASSIGN                 // x = 5
+	TERNARY                // c ? a : b
+	PIPE                   // |>
+	COALESCE               // ??
 	EQUALS                 // ==
-	LESSGREATER            // > or <
+	LESSGREATER            // >, <, >=, or <=
 	SUM                    // +
 	PRODUCT                // *
 	PREFIX                 // -X or !X
@@ -31,14 +37,21 @@ const (
 
 // Assigns parser precedence to tokens
 var precedences = map[token.TokenType]int{
+	token.ASSIGN:   ASSIGN,
+	token.QUESTION: TERNARY,
+	token.PIPE:     PIPE,
+	token.COALESCE: COALESCE,
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.LTE:      LESSGREATER,
+	token.GTE:      LESSGREATER,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.DIVIDE:   PRODUCT,
 	token.MULTIPLY: PRODUCT,
+	token.MODULO:   PRODUCT,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
 }
@@ -51,6 +64,8 @@ type Parser struct {
 	peekToken      token.Token                       // next token
 	prefixParseFns map[token.TokenType]prefixParseFn // hash table to compare prefix and infix expressions
 	infixParseFns  map[token.TokenType]infixParseFn
+	curComments    []string // comments directly before the current token, from a lexer that keeps comments
+	peekComments   []string // comments directly before the peek token
 }
 
 // peekPrecedence returns the precedence operator for peek token, defaults to lowest
@@ -84,26 +99,38 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn) // Initialize prefixParseFns map
 	p.registerPrefix(token.IDENT, p.parseIdentifier)           // Register an Identifier parsing function
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)         // Register an Integer Literal parsing function
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)         // Register a Float Literal parsing function
 	p.registerPrefix(token.STRING, p.parseStringLiteral)       // Register a String Literal expression
 	p.registerPrefix(token.NOT, p.parsePrefixExpression)       // Register a ! prefix expression
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)     // Register a - prefix expression
+	p.registerPrefix(token.NOT_WORD, p.parseNotWordExpression) // Register a 'not' prefix expression, an alias of !
 	p.registerPrefix(token.TRUE, p.parseBoolean)               // Register a TRUE prefix expression
 	p.registerPrefix(token.FALSE, p.parseBoolean)              // Register a False prefix expression
+	p.registerPrefix(token.NULL, p.parseNullLiteral)           // Register a null prefix expression
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)   // Register a ( prefix expression
 	p.registerPrefix(token.IF, p.parseIfExpression)            // Register an IF prefix expression
+	p.registerPrefix(token.WHILE, p.parseWhileExpression)      // Register a WHILE prefix expression
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)   // Register a Function prefix expression
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)      // Register a [ prefix expression for arrays
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)         // Register a { prefix for hash literal expressions
+	p.registerPrefix(token.LET, p.parseLetInExpression)        // Register a let prefix for let-in expressions
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn) // Create a hash table of infix expression tokens
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.DIVIDE, p.parseInfixExpression)
 	p.registerInfix(token.MULTIPLY, p.parseInfixExpression)
+	p.registerInfix(token.MODULO, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LTE, p.parseInfixExpression)
+	p.registerInfix(token.GTE, p.parseInfixExpression)
+	p.registerInfix(token.COALESCE, p.parseInfixExpression)
+	p.registerInfix(token.QUESTION, p.parseTernaryExpression)
+	p.registerInfix(token.PIPE, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression) // Register a ( infix expression for call expressions
 
@@ -120,16 +147,38 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
-// peekError appends errors to message if unexpected token is encountered
+// peekError appends errors to message if unexpected token is encountered, prefixed with the line:col of the token found
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("Expected next token to be %s, got %s instead", t, p.peekToken.Type)
+	msg := fmt.Sprintf("%s: Expected next token to be %s, got %s instead", position(p.peekToken), t, p.peekToken.Type)
 	p.errors = append(p.errors, msg)
 }
 
 // nextToken increments to the next token
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
+	p.curComments = p.peekComments
+	p.peekComments = nil
 	p.peekToken = p.l.NextToken()
+
+	// Comments aren't parsed, they are kept for the statement that follows them. A comment on the same line as the code before it belongs to that code, and a blank line ends a comment block, so neither is kept.
+	lastLine := 0
+
+	for p.peekToken.Type == token.COMMENT {
+		if p.peekToken.Line == p.curToken.Line || p.peekToken.Line > lastLine+1 {
+			p.peekComments = nil
+		}
+
+		if p.peekToken.Line != p.curToken.Line {
+			p.peekComments = append(p.peekComments, p.peekToken.Literal)
+		}
+
+		lastLine = p.peekToken.Line
+		p.peekToken = p.l.NextToken()
+	}
+
+	if p.peekToken.Line > lastLine+1 {
+		p.peekComments = nil
+	}
 }
 
 // sets the current token
@@ -176,21 +225,97 @@ func (p *Parser) ParseProgram() *ast.Program {
 
 	// If statements aren't empty, append program statements until End of File token.
 	for p.curToken.Type != token.EOF {
+		errorCount := len(p.errors)
+
 		stmt := p.parseStatement()
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
+
+		// Skip the rest of a statement that failed to parse, so the following statements are parsed cleanly
+		if len(p.errors) > errorCount {
+			p.synchronize()
+		}
+
 		p.nextToken()
 	}
 	return program
 }
 
-// parseStatement checks token type to determine statement type
+// synchronize advances past the remainder of a bad statement. It stops at the statement's semicolon, at the } closing a block or hash the statement ends with, or just before the next let, const or return statement or the } of the block the statement is in.
+func (p *Parser) synchronize() {
+	// Braces opened while skipping, a } at depth 0 closes something the statement started before its error
+	depth := 0
+
+	for !p.curTokenIs(token.SEMICOLON) && !p.curTokenIs(token.EOF) {
+		switch {
+		case p.curTokenIs(token.LBRACE):
+			depth++
+		case p.curTokenIs(token.RBRACE):
+			depth--
+
+			// An if's first block is followed by its else block, the statement isn't over yet
+			if depth <= 0 && !p.peekTokenIs(token.ELSE) {
+				if p.peekTokenIs(token.SEMICOLON) {
+					p.nextToken()
+				}
+				return
+			}
+		}
+
+		if depth <= 0 && (p.peekTokenIs(token.LET) || p.peekTokenIs(token.CONST) || p.peekTokenIs(token.RETURN) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF)) {
+			return
+		}
+
+		p.nextToken()
+	}
+}
+
+// parseStatement parses a statement and attaches the comments directly before it
 func (p *Parser) parseStatement() ast.Statement {
+	comment := strings.Join(p.curComments, "\n")
+
+	stmt := p.parseStatementType()
+
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		stmt.LeadingComment = comment
+	case *ast.ConstStatement:
+		stmt.LeadingComment = comment
+	case *ast.ReturnStatement:
+		stmt.LeadingComment = comment
+	case *ast.ExpressionStatement:
+		stmt.LeadingComment = comment
+	}
+
+	return stmt
+}
+
+// parseStatementType checks token type to determine statement type
+func (p *Parser) parseStatementType() ast.Statement {
 	switch p.curToken.Type {
 	// Let statement
 	case token.LET:
-		return p.parseLetStatement()
+		// A failed let statement is a nil *ast.LetStatement, return a nil ast.Statement so it isn't added to the program
+		stmt := p.parseLetStatement()
+		if stmt == nil {
+			return nil
+		}
+
+		// let x = 5 in x * 2 is an expression statement, not a let statement
+		if p.peekTokenIs(token.IN) {
+			return p.parseLetInStatement(stmt)
+		}
+
+		return stmt
+	case token.CONST:
+		// Like let, a failed const statement mustn't become a non-nil ast.Statement
+		stmt := p.parseConstStatement()
+		if stmt == nil {
+			return nil
+		}
+
+		return stmt
 	case token.RETURN:
 		return p.parseReturnStatement()
 	default:
@@ -210,6 +335,11 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	// Uses the identifier to create an AST identifier node
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	// Optional type annotation, let x: int = 5;
+	if !p.parseTypeAnnotation(stmt) {
+		return nil
+	}
+
 	// let statement expects a assignment (=)
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -219,6 +349,41 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
+	// A value that failed to parse has already been reported, drop the statement rather than keep a let with no value
+	if stmt.Value == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseConstStatement creates a const statement node, const PI = 3.14;
+func (p *Parser) parseConstStatement() *ast.ConstStatement {
+	stmt := &ast.ConstStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	// Dropped like a let whose value failed to parse
+	if stmt.Value == nil {
+		return nil
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -226,6 +391,77 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
+// parseTypeAnnotation parses an optional ': type' after a let statement's name. It returns false if the ':' isn't followed by a type name.
+func (p *Parser) parseTypeAnnotation(stmt *ast.LetStatement) bool {
+	if !p.peekTokenIs(token.COLON) {
+		return true
+	}
+
+	p.nextToken()
+
+	if !p.expectPeek(token.IDENT) {
+		return false
+	}
+
+	stmt.Type = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return true
+}
+
+// parseLetInStatement finishes a let statement followed by 'in' as an expression statement holding a let-in expression
+func (p *Parser) parseLetInStatement(let *ast.LetStatement) *ast.ExpressionStatement {
+	stmt := &ast.ExpressionStatement{Token: let.Token}
+
+	p.nextToken()
+	stmt.Expression = p.parseLetInBody(let)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parseLetInExpression creates a let-in expression node for a let in expression position, (let x = 5 in x * 2) + 1
+func (p *Parser) parseLetInExpression() ast.Expression {
+	let := &ast.LetStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	let.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.parseTypeAnnotation(let) {
+		return nil
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	let.Value = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	return p.parseLetInBody(let)
+}
+
+// parseLetInBody parses the body after 'in', the current token, and builds the let-in expression. The body extends as far right as possible.
+func (p *Parser) parseLetInBody(let *ast.LetStatement) ast.Expression {
+	exp := &ast.LetInExpression{Token: let.Token, Name: let.Name, Type: let.Type, Value: let.Value}
+
+	p.nextToken()
+
+	exp.Body = p.parseExpression(LOWEST)
+
+	return exp
+}
+
 // parseReturnStatement creates a return statement node
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
@@ -258,6 +494,69 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	return stmt
 }
 
+// parseAssignExpression parses an assignment to an existing binding, x = 5, or to an element, arr[0] = 5. It is right associative, so x = y = 5 assigns 5 to y and then x. Only what isAssignable accepts can be assigned to, "5 = 3;" is rejected here.
+func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
+	exp := &ast.AssignExpression{Token: p.curToken}
+
+	p.nextToken()
+
+	// Parse with a lower precedence than '=' for right associativity
+	value := p.parseExpression(ASSIGN - 1)
+
+	if !isAssignable(left) {
+		p.invalidAssignmentError()
+		return nil
+	}
+
+	switch left := left.(type) {
+	case *ast.Identifier:
+		exp.Name = left
+	case *ast.IndexExpression:
+		exp.Index = left
+	}
+
+	exp.Value = value
+
+	return exp
+}
+
+// parseTernaryExpression parses cond ? a : b. It is right associative, so a ? b : c ? d : e is a ? b : (c ? d : e).
+// A ':' always ends the consequence of the nearest unfinished ternary. So where a ':' also separates a hash key from its value or the bounds of a slice, a ternary is parsed first: {c ? "a" : "b": 1} has the key c ? "a" : "b", and arr[c ? 1 : 2] is an index, not a slice.
+func (p *Parser) parseTernaryExpression(condition ast.Expression) ast.Expression {
+	exp := &ast.TernaryExpression{Token: p.curToken, Condition: condition}
+
+	p.nextToken()
+	exp.Consequence = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+
+	// Parse with a lower precedence than '?' for right associativity
+	exp.Alternative = p.parseExpression(TERNARY - 1)
+
+	return exp
+}
+
+// isAssignable returns true if the expression is a valid assignment target, an identifier or an index expression without a default value
+func isAssignable(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		return true
+	case *ast.IndexExpression:
+		return exp.Default == nil
+	default:
+		return false
+	}
+}
+
+// invalidAssignmentError appends an error for an assignment to something isAssignable rejects
+func (p *Parser) invalidAssignmentError() {
+	p.errors = append(p.errors, "invalid assignment target")
+}
+
 // parseExpression checks if there is a parsing function associated with the current token and assigns it to left expression
 func (p *Parser) parseExpression(precedence int) ast.Expression { // Precedence defaults to LOWEST unless a higher precedence is passed from parseInfixExpression
 
@@ -265,6 +564,12 @@ func (p *Parser) parseExpression(precedence int) ast.Expression { // Precedence
 
 	prefix := p.prefixParseFns[p.curToken.Type] // Checks if there is a prefixParseFn associated with the token type, (i.e. "1 + 2 + 3;", the 1 is an integer literal expression, so it calls parseIntegerLiteral)
 
+	// The lexer couldn't make sense of the input, like an unterminated string, so report its text
+	if p.curTokenIs(token.ILLEGAL) {
+		p.errors = append(p.errors, fmt.Sprintf("%s: Illegal token: %s", position(p.curToken), p.curToken.Literal))
+		return nil
+	}
+
 	if prefix == nil {
 		p.noPrefixParseFnError(p.curToken.Type) // calls noPrefixParseFnError if prefix type is nil
 		return nil
@@ -298,6 +603,13 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	// Convert string value to Int64
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64) // call the parser's current token's literal value and convert to integer
 
+	// Out of range is the common failure and gets its own message, others, like 09 read as octal, keep the general one
+	if errors.Is(err, strconv.ErrRange) {
+		msg := fmt.Sprintf("%s: integer literal too large for 64-bit: %s", position(p.curToken), p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
 	if err != nil {
 		msg := fmt.Sprintf("Could not parse %q as integer", p.curToken.Literal)
 		p.errors = append(p.errors, msg)
@@ -309,6 +621,26 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+// parseFloatLiteral parses floating-point literals
+func (p *Parser) parseFloatLiteral() ast.Expression {
+
+	defer untrace(trace("parseFloatLiteral")) // Call parser_tracing to follow this expression
+
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+
+	if err != nil {
+		msg := fmt.Sprintf("Could not parse %q as float", p.curToken.Literal)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
 // parseStringLiteral parses String Literal expressions, returns the AST identifier and its value as a single string token.
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
@@ -342,22 +674,122 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return bo
 }
 
+// parseNullLiteral parses the null keyword, it doesn't advance the token or call nextToken.
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 // parseArrayLiteral parses elements following an '[' prefix expression through parseExpressionList until the end token ']' is encountered, and returns the list of elements within an ArrayLiteral token.
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
-	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		array.Elements = []ast.Expression{}
+		return array
+	}
+
+	p.nextToken()
+	first := p.parseExpression(LOWEST)
+
+	// [x * 2 for x in arr] is a comprehension rather than a literal
+	if p.peekTokenIs(token.FOR) {
+		return p.parseArrayComprehension(array.Token, first)
+	}
+
+	array.Elements = p.parseRemainingExpressionList(first, token.RBRACKET)
 
 	return array
 }
 
-// parseIndexExpression parses index expressions for arrays
+// parseArrayComprehension parses the rest of an array comprehension after its element expression, the current token, up to the closing ]
+func (p *Parser) parseArrayComprehension(tok token.Token, element ast.Expression) ast.Expression {
+	variable, collection, condition, ok := p.parseComprehensionClause()
+	if !ok || !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return &ast.ArrayComprehension{Token: tok, Element: element, Variable: variable, Collection: collection, Condition: condition}
+}
+
+// parseHashComprehension parses the rest of a hash comprehension after its first key and value, up to the closing }
+func (p *Parser) parseHashComprehension(tok token.Token, key, value ast.Expression) ast.Expression {
+	variable, collection, condition, ok := p.parseComprehensionClause()
+	if !ok || !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+
+	return &ast.HashComprehension{Token: tok, Key: key, Value: value, Variable: variable, Collection: collection, Condition: condition}
+}
+
+// parseComprehensionClause parses "for x in collection", with an optional "if condition", when the next token is for. The condition is nil when left out, and ok is false if the clause is malformed.
+func (p *Parser) parseComprehensionClause() (variable *ast.Identifier, collection, condition ast.Expression, ok bool) {
+	p.nextToken()
+
+	if !p.expectPeek(token.IDENT) {
+		return nil, nil, nil, false
+	}
+
+	variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil, nil, nil, false
+	}
+
+	p.nextToken()
+	collection = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		condition = p.parseExpression(LOWEST)
+	}
+
+	return variable, collection, condition, true
+}
+
+// parseIndexExpression parses index expressions for arrays and hashes, an optional default value may follow the index after a comma
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
 
 	p.nextToken()
+
+	// arr[:high]
+	if p.curTokenIs(token.COLON) {
+		return p.parseSliceExpression(exp.Token, left, nil)
+	}
+
 	exp.Index = p.parseExpression(LOWEST)
 
+	// arr[low:] or arr[low:high]
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		return p.parseSliceExpression(exp.Token, left, exp.Index)
+	}
+
+	// hash["key", default]
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		exp.Default = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return exp
+}
+
+// parseSliceExpression parses the rest of a slice after its ':', the current token, up to the closing ]
+func (p *Parser) parseSliceExpression(tok token.Token, left, low ast.Expression) ast.Expression {
+	exp := &ast.SliceExpression{Token: tok, Left: left, Low: low}
+
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		exp.High = p.parseExpression(LOWEST)
+	}
+
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
@@ -381,7 +813,13 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 
+		// {k: v for x in arr} is a comprehension rather than a literal
+		if len(hash.Pairs) == 0 && p.peekTokenIs(token.FOR) {
+			return p.parseHashComprehension(hash.Token, key, value)
+		}
+
 		hash.Pairs[key] = value
+		hash.Order = append(hash.Order, key)
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -405,9 +843,15 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 		return list
 	}
 
-	// Advance to the next token and append the element to the list
+	// Advance to the next token and parse the rest of the list from its first element
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
+
+	return p.parseRemainingExpressionList(p.parseExpression(LOWEST), end)
+}
+
+// parseRemainingExpressionList parses the elements of a list after its first, already parsed, element up to the end token
+func (p *Parser) parseRemainingExpressionList(first ast.Expression, end token.TokenType) []ast.Expression {
+	list := []ast.Expression{first}
 
 	// If the next token is a comma, advance twice
 	for p.peekTokenIs(token.COMMA) {
@@ -443,10 +887,29 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 	return expression
 }
 
-// noPrefixParseFnError appends invalid type information for prefix expressions to parser errors
+// parseNotWordExpression parses the 'not' keyword as a ! prefix expression, so 'not done' and '!done' produce the same AST node
+func (p *Parser) parseNotWordExpression() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token:    token.Token{Type: token.NOT, Literal: "!"},
+		Operator: "!",
+	}
+
+	p.nextToken()
+
+	expression.Right = p.parseExpression(PREFIX)
+
+	return expression
+}
+
+// noPrefixParseFnError appends invalid type information for prefix expressions to parser errors, prefixed with the line:col of the current token
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("Invalid prefix operator, type: %s", t) // If there isn't a valid prefix expression type, throw an error and return the actual type.
-	p.errors = append(p.errors, msg)                           // Append error message to parser errors
+	msg := fmt.Sprintf("%s: Invalid prefix operator, type: %s", position(p.curToken), t) // If there isn't a valid prefix expression type, throw an error and return the actual type.
+	p.errors = append(p.errors, msg)                                                     // Append error message to parser errors
+}
+
+// position formats where a token starts as line:col for error messages
+func position(tok token.Token) string {
+	return fmt.Sprintf("%d:%d", tok.Line, tok.Column)
 }
 
 // parseInfixExpression creates an infix expression node
@@ -468,12 +931,26 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
-// parseGroupedExpression parses grouped expressions, "12 / (2+2)" == "(12 / (2+2))"
+// parseGroupedExpression parses grouped expressions, "12 / (2+2)" == "(12 / (2+2))". A group containing commas is a sequence expression, "(a, b, c)".
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	tok := p.curToken
+
 	p.nextToken()
 
 	exp := p.parseExpression(LOWEST)
 
+	if p.peekTokenIs(token.COMMA) {
+		sequence := &ast.SequenceExpression{Token: tok, Expressions: []ast.Expression{exp}}
+
+		for p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+			p.nextToken()
+			sequence.Expressions = append(sequence.Expressions, p.parseExpression(LOWEST))
+		}
+
+		exp = sequence
+	}
+
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
@@ -517,6 +994,32 @@ func (p *Parser) parseIfExpression() ast.Expression { // Create an AST expressio
 	return expression // Results of If expression
 }
 
+// parseWhileExpression parses a while loop, while (condition) { body }
+func (p *Parser) parseWhileExpression() ast.Expression {
+
+	expression := &ast.WhileExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	expression.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+
+	return expression
+}
+
 // parseBlockStatement parses IF block statements, similar to parseStatement function
 func (p *Parser) parseBlockStatement() *ast.BlockStatement { // Create an AST node for block statements
 	block := &ast.BlockStatement{Token: p.curToken} // Insert current token into AST node
@@ -525,12 +1028,19 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement { // Create an AST no
 	p.nextToken() // Call next token
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) { // Continue looping until } or EOF is encountered
+		errorCount := len(p.errors)
+
 		stmt := p.parseStatement() // Parse block statement
 
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt) // Append token to array
 		}
 
+		// Like ParseProgram, skip the rest of a bad statement so the block's other statements and its } are parsed cleanly
+		if len(p.errors) > errorCount {
+			p.synchronize()
+		}
+
 		p.nextToken() // Call next token
 	}
 
@@ -546,7 +1056,7 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil // ExpectPeek returns a parser error if token isn't the expected type
 	}
 
-	lit.Parameters = p.parseFunctionParameters() // Parse the function's parameters with parseFunctionParameters()
+	lit.Parameters, lit.Defaults = p.parseFunctionParameters() // Parse the function's parameters and their default values with parseFunctionParameters()
 
 	if !p.expectPeek(token.LBRACE) { // End if token after fn name isn't a "{"
 		return nil // ExpectPeek returns a parser error if token isn't the expected type
@@ -557,37 +1067,54 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit // Final functionLiteral expression
 }
 
-// parseFunctionParameters parses function literal expression's parameters
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+// parseFunctionParameters parses function literal expression's parameters, and the default values of parameters written as y = 10. Parameters with defaults must come after the ones without.
+func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, map[string]ast.Expression) {
 
 	identifiers := []*ast.Identifier{} // Assign to an array of AST identifiers (function parameters)
+	var defaults map[string]ast.Expression
 
 	// If next token is ")", advance parser to next token and return empty array of parameters
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return identifiers, defaults
 	}
 
-	p.nextToken() // Advance to token following "("
+	// For each parameter, advance to its name, then parse its default value if it is followed by "="
+	for {
+		p.nextToken()
 
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal} // Define a single parameter, assign it to an AST identifier with Token type and value
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal} // Define a single parameter, assign it to an AST identifier with Token type and value
 
-	identifiers = append(identifiers, ident) // Append individual identifier to the array of identifiers (parameters)
+		identifiers = append(identifiers, ident) // Append individual identifier to the array of identifiers (parameters)
+
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+
+			if defaults == nil {
+				defaults = make(map[string]ast.Expression)
+			}
+
+			defaults[ident.Value] = p.parseExpression(LOWEST)
+		} else if defaults != nil {
+			msg := fmt.Sprintf("parameter %s without a default value follows a parameter with one", ident.Value)
+			p.errors = append(p.errors, msg)
+		}
+
+		// When next token is "," -- advance parser to the comma, the loop moves to the next parameter
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 
-	// When next token is "," -- advance parser twice to move to the token after comma, assign the identifier (parameter)
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
 		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
 	}
 
 	// An ")" is expected to follow the parameter list, if this is false, return peek error
 	if !p.expectPeek(token.RPAREN) {
-		return nil
+		return nil, nil
 	}
 
-	return identifiers // Final parameter list
+	return identifiers, defaults // Final parameter list
 }
 
 // parseCallExpressions parses call expressions
@@ -597,12 +1124,30 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 
 	// Parse call expression arguments
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Arguments = p.parseNamedArguments(p.parseExpressionList(token.RPAREN))
 	//exp.Arguments = p.parseCallArguments() // old version
 
 	return exp
 }
 
+// parseNamedArguments turns assignments in a call's argument list into named arguments, f(y = 3) passes 3 as y rather than assigning to y. Positional arguments can't follow a named argument.
+func (p *Parser) parseNamedArguments(args []ast.Expression) []ast.Expression {
+	named := false
+
+	for i, arg := range args {
+		// An element assignment, f(arr[0] = 1), stays a positional argument
+		if assign, ok := arg.(*ast.AssignExpression); ok && assign.Name != nil {
+			args[i] = &ast.NamedArgument{Token: assign.Name.Token, Name: assign.Name, Value: assign.Value}
+			named = true
+		} else if named {
+			p.errors = append(p.errors, "positional argument follows named argument")
+			return args
+		}
+	}
+
+	return args
+}
+
 // parseCallArguments parses call expression arguments
 func (p *Parser) parseCallArguments() []ast.Expression {
 	args := []ast.Expression{} // Put each arg into an array of AST expression nodes
@@ -119,6 +119,60 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+// TestConstStatements tests parsing const statements
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedIdentifier string
+		expectedValue      interface{}
+		expectedString     string
+	}{
+		{"const PI = 3;", "PI", 3, "const PI = 3;"},
+		{"const ok = true", "ok", true, "const ok = true;"},
+		{"const f = x;", "f", "x", "const f = x;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ConstStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.ConstStatement. got=%T", program.Statements[0])
+		}
+
+		if stmt.TokenLiteral() != "const" {
+			t.Errorf("stmt.TokenLiteral not 'const'. got=%q", stmt.TokenLiteral())
+		}
+
+		testIdentifier(t, stmt.Name, tt.expectedIdentifier)
+		testLiteralExpression(t, stmt.Value, tt.expectedValue)
+
+		if stmt.String() != tt.expectedString {
+			t.Errorf("stmt.String() wrong. expected=%q, got=%q", tt.expectedString, stmt.String())
+		}
+	}
+
+	p := New(lexer.New("const 5 = 3;"))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected a parser error for a const without a name")
+	}
+
+	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.ConstStatement); ok {
+			t.Errorf("a failed const statement was added to the program")
+		}
+	}
+}
+
 // testLetStatement must contain test case, AST statement with TokenLiteral "let", and identifier to return true.
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
@@ -329,6 +383,47 @@ func TestParsingPrefixExpressions(t *testing.T) {
 	}
 }
 
+// TestParsingNotKeyword tests that the 'not' keyword parses to the same AST as the ! prefix
+func TestParsingNotKeyword(t *testing.T) {
+	tests := []struct {
+		wordInput   string
+		symbolInput string
+	}{
+		{"not done;", "!done;"},
+		{"not true;", "!true;"},
+		{"not not 8;", "!!8;"},
+		{"not (5 < 10);", "!(5 < 10);"},
+	}
+
+	for _, tt := range tests {
+		wordParser := New(lexer.New(tt.wordInput))
+		wordProgram := wordParser.ParseProgram()
+		checkParserErrors(t, wordParser)
+
+		symbolParser := New(lexer.New(tt.symbolInput))
+		symbolProgram := symbolParser.ParseProgram()
+		checkParserErrors(t, symbolParser)
+
+		if wordProgram.String() != symbolProgram.String() {
+			t.Errorf("%q parsed to %q, want %q", tt.wordInput, wordProgram.String(), symbolProgram.String())
+		}
+
+		stmt, ok := wordProgram.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", wordProgram.Statements[0])
+		}
+
+		exp, ok := stmt.Expression.(*ast.PrefixExpression)
+		if !ok {
+			t.Fatalf("%q is not ast.PrefixExpression. got=%T", tt.wordInput, stmt.Expression)
+		}
+
+		if exp.Operator != "!" {
+			t.Errorf("exp.Operator is not '!'. got=%s", exp.Operator)
+		}
+	}
+}
+
 // TestParsingInfixExpressions tests parsing of infix expressions
 func TestParsingInfixExpressions(t *testing.T) {
 	infixTests := []struct {
@@ -344,6 +439,8 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 / 5;", 5, "/", 5},
 		{"5 > 5;", 5, ">", 5},
 		{"5 < 5;", 5, "<", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 <= 5;", 5, "<=", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
 
@@ -438,6 +535,14 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"5 > 4 == 3 < 4",
 			"((5 > 4) == (3 < 4))",
 		},
+		{
+			"5 >= 4 == 3 <= 4",
+			"((5 >= 4) == (3 <= 4))",
+		},
+		{
+			"a + b <= c * d",
+			"((a + b) <= (c * d))",
+		},
 		{
 			"5 < 4 != 3 > 4",
 			"((5 < 4) != (3 > 4))",
@@ -501,6 +606,83 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])),(b[1]),(2 * ([1, 2][1])))",
 		},
+		// Test sequence expressions
+		{
+			"(a, b + c, d)",
+			"(a, (b + c), d)",
+		},
+		{
+			"add((a, b), c)",
+			"add((a, b),c)",
+		},
+		{
+			"(a, (b, c)) * 2",
+			"((a, (b, c)) * 2)",
+		},
+		// Test null coalescing precedence
+		{
+			"a ?? b ?? c",
+			"((a ?? b) ?? c)",
+		},
+		{
+			"a ?? b == c",
+			"(a ?? (b == c))",
+		},
+		{
+			"null ?? 1 + 2",
+			"(null ?? (1 + 2))",
+		},
+		// Test modulo precedence
+		{
+			"a + b % c",
+			"(a + (b % c))",
+		},
+		{
+			"a * b % c",
+			"((a * b) % c)",
+		},
+		// Test pipeline precedence
+		{
+			"x |> f |> g",
+			"((x |> f) |> g)",
+		},
+		{
+			"a + b |> f",
+			"((a + b) |> f)",
+		},
+		{
+			"a ?? b |> f",
+			"((a ?? b) |> f)",
+		},
+		{
+			"x |> f(1)",
+			"(x |> f(1))",
+		},
+		// Test ternary precedence
+		{
+			"a > b ? a : b",
+			"((a > b) ? a : b)",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a ? b ? c : d : e",
+			"(a ? (b ? c : d) : e)",
+		},
+		{
+			"a ?? b ? c + 1 : d * 2",
+			"((a ?? b) ? (c + 1) : (d * 2))",
+		},
+		{
+			"x = a ? b : c",
+			"(x = (a ? b : c))",
+		},
+		{
+			"x |> f ? a : b",
+			"((x |> f) ? a : b)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -516,6 +698,86 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 	}
 }
 
+// TestIntegerLiteralTooLarge tests that an integer literal beyond int64 gives a specific parser error
+func TestIntegerLiteralTooLarge(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1234567890123456789012345678901234567890;", "1:1: integer literal too large for 64-bit: 1234567890123456789012345678901234567890"},
+		{"let x = 9223372036854775808;", "1:9: integer literal too large for 64-bit: 9223372036854775808"},
+		{"-9223372036854775808", "1:2: integer literal too large for 64-bit: 9223372036854775808"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tt.expected {
+			t.Errorf("%q: wrong parser errors. expected %q first, got=%q", tt.input, tt.expected, p.Errors())
+		}
+	}
+
+	p := New(lexer.New("9223372036854775807"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	testIntegerLiteral(t, program.Statements[0].(*ast.ExpressionStatement).Expression, 9223372036854775807)
+}
+
+// TestParsingTernaryExpression tests parsing cond ? a : b, and that a ':' inside a hash literal or an index goes to an unfinished ternary first
+func TestParsingTernaryExpression(t *testing.T) {
+	l := lexer.New(`(5 > 3) ? "yes" : "no"`)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	exp, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.TernaryExpression)
+	if !ok {
+		t.Fatalf("expression is not ast.TernaryExpression. got=%T", program.Statements[0].(*ast.ExpressionStatement).Expression)
+	}
+
+	testInfixExpression(t, exp.Condition, 5, ">", 3)
+
+	for _, branch := range []struct {
+		exp      ast.Expression
+		expected string
+	}{{exp.Consequence, "yes"}, {exp.Alternative, "no"}} {
+		str, ok := branch.exp.(*ast.StringLiteral)
+		if !ok || str.Value != branch.expected {
+			t.Errorf("branch is not the string %q. got=%T %q", branch.expected, branch.exp, branch.exp.String())
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"k": c ? 1 : 2}`, "{k:(c ? 1 : 2)}"},
+		{`{c ? "a" : "b": 1}`, "{(c ? a : b):1}"},
+		{`arr[c ? 1 : 2]`, "(arr[(c ? 1 : 2)])"},
+		{`f(a ? b : c, d)`, "f((a ? b : c),d)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("%q: expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	p = New(lexer.New("a ? b;"))
+	p.ParseProgram()
+
+	expected := "1:6: Expected next token to be :, got ; instead"
+	if len(p.Errors()) == 0 || p.Errors()[0] != expected {
+		t.Errorf("wrong parser errors. expected %q first, got=%q", expected, p.Errors())
+	}
+}
+
 // testIntegerLiteral is generalized integer literal test to verify the current integerLiteral matches its ast.Expression, has the same token type and literal value
 func testIntegerLiteral(t *testing.T, il ast.Expression, value int64) bool {
 	integ, ok := il.(*ast.IntegerLiteral)
@@ -1081,6 +1343,45 @@ func TestParsingIndexExpressions(t *testing.T) {
 	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
 		return
 	}
+
+	if indexExp.Default != nil {
+		t.Errorf("indexExp.Default is not nil. got=%q", indexExp.Default.String())
+	}
+}
+
+// TestParsingIndexExpressionsWithDefault tests parsing of index expressions with a default value, hash["key", default]
+func TestParsingIndexExpressionsWithDefault(t *testing.T) {
+	input := `myHash["k", 2 * 3]`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+
+	if !ok {
+		t.Fatalf("expression not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, indexExp.Left, "myHash") {
+		return
+	}
+
+	str, ok := indexExp.Index.(*ast.StringLiteral)
+	if !ok || str.Value != "k" {
+		t.Fatalf("indexExp.Index is not the string \"k\". got=%T (%+v)", indexExp.Index, indexExp.Index)
+	}
+
+	if !testInfixExpression(t, indexExp.Default, 2, "*", 3) {
+		return
+	}
+
+	if program.String() != "(myHash[k, (2 * 3)])" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
 }
 
 // TestParsingHashLiteralStringKeys test the parsing of hash literal string keys
@@ -1276,3 +1577,907 @@ func TestParsingEmptyHashLiteral(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
 }
+
+// TestInvalidAssignmentTarget tests that assigning to something other than an identifier or index expression is a parser error
+func TestInvalidAssignmentTarget(t *testing.T) {
+	tests := []string{
+		"5 = 3;",
+		`"x" = 1;`,
+		"add(1, 2) = 3;",
+		"(a + b) = 4;",
+		`h["a", 0] = 5;`,
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("%q: expected 1 parser error, got=%d %q", input, len(errors), errors)
+			continue
+		}
+
+		if errors[0] != "invalid assignment target" {
+			t.Errorf("%q: wrong parser error. expected=%q, got=%q", input, "invalid assignment target", errors[0])
+		}
+	}
+}
+
+// TestParserRecovery tests that a statement that fails to parse doesn't produce cascading errors or swallow the statements after it
+func TestParserRecovery(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+		expectedLets   []string // names bound by the let and const statements parsed
+	}{
+		{
+			"let = 5; let y = 10; let 7;",
+			[]string{
+				"1:5: Expected next token to be IDENT, got = instead",
+				"1:26: Expected next token to be IDENT, got INT instead",
+			},
+			[]string{"y"},
+		},
+		{
+			"let x 5 * 2 let y = 10; let z = ; let w = 3;",
+			[]string{
+				"1:7: Expected next token to be =, got INT instead",
+				"1:33: Invalid prefix operator, type: ;",
+			},
+			[]string{"y", "w"},
+		},
+		{
+			"let a = 1;\nlet = 2\nreturn a;",
+			[]string{
+				"2:5: Expected next token to be IDENT, got = instead",
+			},
+			[]string{"a"},
+		},
+		{
+			"let x 5 const c = 1; let y = 2;\nconst = 3 const d = 4;",
+			[]string{
+				"1:7: Expected next token to be =, got INT instead",
+				"2:7: Expected next token to be IDENT, got = instead",
+			},
+			[]string{"c", "y", "d"},
+		},
+		{
+			"const e = ; let f = 1;",
+			[]string{
+				"1:11: Invalid prefix operator, type: ;",
+			},
+			[]string{"f"},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("%q: wrong number of errors. expected=%d, got=%d %q", tt.input, len(tt.expectedErrors), len(errors), errors)
+			continue
+		}
+
+		for i, msg := range tt.expectedErrors {
+			if errors[i] != msg {
+				t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, msg, errors[i])
+			}
+		}
+
+		lets := []string{}
+		for _, stmt := range program.Statements {
+			switch stmt := stmt.(type) {
+			case *ast.LetStatement:
+				lets = append(lets, stmt.Name.Value)
+			case *ast.ConstStatement:
+				lets = append(lets, stmt.Name.Value)
+			}
+		}
+
+		if fmt.Sprint(lets) != fmt.Sprint(tt.expectedLets) {
+			t.Errorf("%q: wrong let statements parsed. expected=%v, got=%v", tt.input, tt.expectedLets, lets)
+		}
+	}
+}
+
+// TestParserRecoveryAfterBlock tests that a bad statement ending in } doesn't swallow the statement after it, and that a bad statement inside a block only skips to the end of that statement
+func TestParserRecoveryAfterBlock(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+		expectedLast   string // the last top-level statement
+		expectedBody   string // the statements of the first statement's function body, when it has one
+	}{
+		{
+			"let f = fn(){ 5 = 3 }\nputs(1);",
+			[]string{"invalid assignment target"},
+			"puts(1)",
+			"",
+		},
+		{
+			"let f = fn() { let = 1; let a = 2; a }; let g = 3;",
+			[]string{"1:20: Expected next token to be IDENT, got = instead"},
+			"let g = 3;",
+			"let a = 2;a",
+		},
+		{
+			"let f = fn() { if (x) { let = 1; 3 } 4 }\nputs(3);",
+			[]string{"1:29: Expected next token to be IDENT, got = instead"},
+			"puts(3)",
+			"ifx 34",
+		},
+		{
+			"if (x) { let = 1 } else { 2 }\nputs(2);",
+			[]string{"1:14: Expected next token to be IDENT, got = instead"},
+			"puts(2)",
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+
+		if fmt.Sprint(p.Errors()) != fmt.Sprint(tt.expectedErrors) {
+			t.Errorf("%q: wrong errors. expected=%q, got=%q", tt.input, tt.expectedErrors, p.Errors())
+		}
+
+		if len(program.Statements) == 0 {
+			t.Errorf("%q: no statements parsed", tt.input)
+			continue
+		}
+
+		if last := program.Statements[len(program.Statements)-1].String(); last != tt.expectedLast {
+			t.Errorf("%q: wrong last statement. expected=%q, got=%q", tt.input, tt.expectedLast, last)
+		}
+
+		if tt.expectedBody == "" {
+			continue
+		}
+
+		let, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Errorf("%q: first statement is not *ast.LetStatement. got=%T", tt.input, program.Statements[0])
+			continue
+		}
+
+		if body := let.Value.(*ast.FunctionLiteral).Body.String(); body != tt.expectedBody {
+			t.Errorf("%q: wrong function body. expected=%q, got=%q", tt.input, tt.expectedBody, body)
+		}
+	}
+}
+
+// TestParsingSequenceExpression tests parsing of parenthesized comma separated expressions
+func TestParsingSequenceExpression(t *testing.T) {
+	input := `(puts("a"), 5)`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	sequence, ok := stmt.Expression.(*ast.SequenceExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.SequenceExpression. got=%T", stmt.Expression)
+	}
+
+	if len(sequence.Expressions) != 2 {
+		t.Fatalf("wrong number of expressions. want=2, got=%d", len(sequence.Expressions))
+	}
+
+	if _, ok := sequence.Expressions[0].(*ast.CallExpression); !ok {
+		t.Errorf("sequence.Expressions[0] is not ast.CallExpression. got=%T", sequence.Expressions[0])
+	}
+
+	testIntegerLiteral(t, sequence.Expressions[1], 5)
+
+	// A single grouped expression is not a sequence
+	p = New(lexer.New("(5)"))
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if _, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.SequenceExpression); ok {
+		t.Errorf("(5) parsed as ast.SequenceExpression")
+	}
+}
+
+// TestParsingLetInExpression tests let-in expressions, both as a statement and in expression position
+func TestParsingLetInExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 5 in x * 2", "(let x = 5 in (x * 2))"},
+		{"let x = 5 in x * 2;", "(let x = 5 in (x * 2))"},
+		{"(let x = 5 in x) + 1", "((let x = 5 in x) + 1)"},
+		{"let x = 1 in let y = 2 in x + y", "(let x = 1 in (let y = 2 in (x + y)))"},
+		{"let x = 5; x", "let x = 5;x"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	program := New(lexer.New("let x = 5 in x * 2")).ParseProgram()
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.LetInExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.LetInExpression. got=%T", stmt.Expression)
+	}
+
+	if exp.Name.Value != "x" {
+		t.Errorf("exp.Name.Value not 'x'. got=%s", exp.Name.Value)
+	}
+
+	testIntegerLiteral(t, exp.Value, 5)
+	testInfixExpression(t, exp.Body, "x", "*", 2)
+
+	// In expression position the 'in' is required
+	p := New(lexer.New("(let x = 5)"))
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected a parser error for a let without 'in' in expression position")
+	}
+}
+
+// TestFloatLiteralExpression tests parsing a float literal
+func TestFloatLiteralExpression(t *testing.T) {
+	input := "3.14;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("Program should only have 1 statement for float literal expression. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not an ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	literal, ok := stmt.Expression.(*ast.FloatLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != 3.14 {
+		t.Errorf("literal.Value not %g. got=%g", 3.14, literal.Value)
+	}
+
+	if literal.TokenLiteral() != "3.14" {
+		t.Errorf("literal.TokenLiteral not %s. got=%s", "3.14", literal.TokenLiteral())
+	}
+}
+
+// TestWhileExpression tests parsing a while loop
+func TestWhileExpression(t *testing.T) {
+	input := `while (x < y) { x }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Body does not contain %d statements. got=%d\n", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.WhileExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.WhileExpression. got=%T", stmt.Expression)
+	}
+
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(exp.Body.Statements) != 1 {
+		t.Fatalf("body is not 1 statement. got=%d\n", len(exp.Body.Statements))
+	}
+
+	body, ok := exp.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", exp.Body.Statements[0])
+	}
+
+	testIdentifier(t, body.Expression, "x")
+}
+
+// TestLetTypeAnnotation tests parsing let statements with and without a type annotation
+func TestLetTypeAnnotation(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedName  string
+		expectedType  string
+		expectedValue interface{}
+	}{
+		{"let x: int = 5;", "x", "int", 5},
+		{"let name: string = y;", "name", "string", "y"},
+		{"let x = 5;", "x", "", 5},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.LetStatement. got=%T", program.Statements[0])
+		}
+
+		if stmt.Name.Value != tt.expectedName {
+			t.Errorf("stmt.Name.Value not %q. got=%q", tt.expectedName, stmt.Name.Value)
+		}
+
+		if tt.expectedType == "" {
+			if stmt.Type != nil {
+				t.Errorf("stmt.Type is not nil. got=%q", stmt.Type.Value)
+			}
+		} else if stmt.Type == nil || stmt.Type.Value != tt.expectedType {
+			t.Errorf("stmt.Type is not %q. got=%v", tt.expectedType, stmt.Type)
+		}
+
+		testLiteralExpression(t, stmt.Value, tt.expectedValue)
+
+		if stmt.String() != tt.input {
+			t.Errorf("stmt.String() wrong. expected=%q, got=%q", tt.input, stmt.String())
+		}
+	}
+
+	p := New(lexer.New("let x: = 5;"))
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected a parser error for a missing type name")
+	}
+}
+
+// TestFunctionParameterDefaults tests parsing default parameter values
+func TestFunctionParameterDefaults(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedParams   []string
+		expectedDefaults map[string]string
+		expectedString   string
+	}{
+		{"fn(x, y = 10) {};", []string{"x", "y"}, map[string]string{"y": "10"}, "fn(x,y = 10)"},
+		{"fn(x = 1 + 2) {};", []string{"x"}, map[string]string{"x": "(1 + 2)"}, "fn(x = (1 + 2))"},
+		{"fn(x, y = x * 2, z = [1]) {};", []string{"x", "y", "z"}, map[string]string{"y": "(x * 2)", "z": "[1]"}, "fn(x,y = (x * 2),z = [1])"},
+		{"fn(x, y) {};", []string{"x", "y"}, map[string]string{}, "fn(x,y)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Fatalf("length of parameters is wrong, expected %d, got =%d\n", len(tt.expectedParams), len(function.Parameters))
+		}
+
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+
+		if len(function.Defaults) != len(tt.expectedDefaults) {
+			t.Errorf("wrong number of defaults, expected %d, got=%d", len(tt.expectedDefaults), len(function.Defaults))
+		}
+
+		for name, expected := range tt.expectedDefaults {
+			def, ok := function.Defaults[name]
+			if !ok {
+				t.Errorf("no default for parameter %s", name)
+				continue
+			}
+
+			if def.String() != expected {
+				t.Errorf("wrong default for %s, expected %q, got=%q", name, expected, def.String())
+			}
+		}
+
+		if function.String() != tt.expectedString {
+			t.Errorf("function.String() wrong, expected %q, got=%q", tt.expectedString, function.String())
+		}
+	}
+
+	p := New(lexer.New("fn(x = 1, y) {};"))
+	p.ParseProgram()
+
+	expected := "parameter y without a default value follows a parameter with one"
+	if len(p.Errors()) != 1 || p.Errors()[0] != expected {
+		t.Errorf("wrong parser errors, expected %q, got=%q", expected, p.Errors())
+	}
+}
+
+// TestParsingAssignExpression tests assignment expressions and their right associativity
+func TestParsingAssignExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5;", "(x = 5)"},
+		{"x = x + 1;", "(x = (x + 1))"},
+		{"x = y = 5;", "(x = (y = 5))"},
+		{"x = y |> f;", "(x = (y |> f))"},
+		{"x = 1 == 2;", "(x = (1 == 2))"},
+		{"arr[0] = 5;", "((arr[0]) = 5)"},
+		{`h["a"] = h["b"] = 1;`, "((h[a]) = ((h[b]) = 1))"},
+		{"f(arr[0] = 1)", "f(((arr[0]) = 1))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	program := New(lexer.New("x = 5;")).ParseProgram()
+
+	exp, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.AssignExpression)
+	if !ok {
+		t.Fatalf("expression is not ast.AssignExpression. got=%T", program.Statements[0].(*ast.ExpressionStatement).Expression)
+	}
+
+	testIdentifier(t, exp.Name, "x")
+	testIntegerLiteral(t, exp.Value, 5)
+}
+
+// TestParsingNamedArguments tests that assignments in a call's arguments are named arguments
+func TestParsingNamedArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(y = 3, x = 1);", "f(y = 3,x = 1)"},
+		{"f(1, y = 2 + 3);", "f(1,y = (2 + 3))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	program := New(lexer.New("f(1, y = 3);")).ParseProgram()
+	call := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+
+	testIntegerLiteral(t, call.Arguments[0], 1)
+
+	arg, ok := call.Arguments[1].(*ast.NamedArgument)
+	if !ok {
+		t.Fatalf("call.Arguments[1] is not ast.NamedArgument. got=%T", call.Arguments[1])
+	}
+
+	testIdentifier(t, arg.Name, "y")
+	testIntegerLiteral(t, arg.Value, 3)
+
+	p := New(lexer.New("f(y = 3, 1);"))
+	p.ParseProgram()
+
+	expected := "positional argument follows named argument"
+	if len(p.Errors()) != 1 || p.Errors()[0] != expected {
+		t.Errorf("wrong parser errors. expected=%q, got=%q", expected, p.Errors())
+	}
+}
+
+// TestLeadingComments tests that comments from a lexer that keeps them are attached to the statement that follows
+func TestLeadingComments(t *testing.T) {
+	input := `// add returns the sum of a and b
+// for integers or strings
+let add = fn(a, b) {
+	// the sum
+	return a + b;
+};
+
+add(1, 2);
+// last
+add(3, 4);`
+
+	p := New(lexer.NewWithComments(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
+	}
+
+	let, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.LetStatement. got=%T", program.Statements[0])
+	}
+
+	expected := "add returns the sum of a and b\nfor integers or strings"
+	if let.LeadingComment != expected {
+		t.Errorf("wrong let comment. expected=%q, got=%q", expected, let.LeadingComment)
+	}
+
+	body := let.Value.(*ast.FunctionLiteral).Body.Statements[0].(*ast.ReturnStatement)
+	if body.LeadingComment != "the sum" {
+		t.Errorf("wrong return comment. expected=%q, got=%q", "the sum", body.LeadingComment)
+	}
+
+	if comment := program.Statements[1].(*ast.ExpressionStatement).LeadingComment; comment != "" {
+		t.Errorf("uncommented statement has a comment. got=%q", comment)
+	}
+
+	if comment := program.Statements[2].(*ast.ExpressionStatement).LeadingComment; comment != "last" {
+		t.Errorf("wrong expression comment. expected=%q, got=%q", "last", comment)
+	}
+
+	// The default lexer skips comments, so nothing is attached
+	program = New(lexer.New(input)).ParseProgram()
+
+	if comment := program.Statements[0].(*ast.LetStatement).LeadingComment; comment != "" {
+		t.Errorf("comment attached without a lexer that keeps comments. got=%q", comment)
+	}
+}
+
+// TestTrailingComments tests that a comment after code on the same line, or separated from the next statement by a blank line, isn't attached to the next statement
+func TestTrailingComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = 1; // x is temporary\nlet y = 2;", ""},
+		{"let x = 1; // x is temporary\n// y is kept\nlet y = 2;", "y is kept"},
+		{"let x = 1;\n// about nothing\n\nlet y = 2;", ""},
+		{"let x = 1;\n// dropped\n\n// y is kept\nlet y = 2;", "y is kept"},
+		{"let x = 1;\n// y is kept\n// over two lines\nlet y = 2;", "y is kept\nover two lines"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.NewWithComments(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 2 {
+			t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+		}
+
+		if comment := program.Statements[0].(*ast.LetStatement).LeadingComment; comment != "" {
+			t.Errorf("first statement has a comment for %q. got=%q", tt.input, comment)
+		}
+
+		if comment := program.Statements[1].(*ast.LetStatement).LeadingComment; comment != tt.expected {
+			t.Errorf("wrong comment for %q. expected=%q, got=%q", tt.input, tt.expected, comment)
+		}
+	}
+}
+
+// TestParsingSliceExpressions tests slices with and without each bound
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		low      string // "" when the bound is left out
+		high     string
+	}{
+		{"arr[1:3]", "(arr[1:3])", "1", "3"},
+		{"arr[:2]", "(arr[:2])", "", "2"},
+		{"arr[2:]", "(arr[2:])", "2", ""},
+		{"arr[:]", "(arr[:])", "", ""},
+		{"arr[i + 1:n]", "(arr[(i + 1):n])", "(i + 1)", "n"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+		slice, ok := stmt.Expression.(*ast.SliceExpression)
+		if !ok {
+			t.Fatalf("exp not *ast.SliceExpression. got=%T", stmt.Expression)
+		}
+
+		if slice.String() != tt.expected {
+			t.Errorf("slice.String() wrong. expected=%q, got=%q", tt.expected, slice.String())
+		}
+
+		testIdentifier(t, slice.Left, "arr")
+
+		if bound := boundString(slice.Low); bound != tt.low {
+			t.Errorf("slice.Low wrong. expected=%q, got=%q", tt.low, bound)
+		}
+
+		if bound := boundString(slice.High); bound != tt.high {
+			t.Errorf("slice.High wrong. expected=%q, got=%q", tt.high, bound)
+		}
+	}
+}
+
+// boundString returns a slice bound's String, or "" for a bound that was left out
+func boundString(exp ast.Expression) string {
+	if exp == nil {
+		return ""
+	}
+
+	return exp.String()
+}
+
+// TestParsingChainedIndexExpressions tests that index expressions chain onto calls and other index expressions
+func TestParsingChainedIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		inner    string // String of the expression being indexed by the outer index
+	}{
+		{"f()[0]", "(f()[0])", "f()"},
+		{"arr[0][1]", "((arr[0])[1])", "(arr[0])"},
+		{`hash["a"]["b"]`, "((hash[a])[b])", "(hash[a])"},
+		{"getArray()[2]", "(getArray()[2])", "getArray()"},
+		{"matrix[1][0][2]", "(((matrix[1])[0])[2])", "((matrix[1])[0])"},
+		{"f()[0]()", "(f()[0])()", "f()"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: program.Statements does not contain 1 statement. got=%d", tt.input, len(program.Statements))
+		}
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+		if stmt.Expression.String() != tt.expected {
+			t.Errorf("%q: wrong String. expected=%q, got=%q", tt.input, tt.expected, stmt.Expression.String())
+		}
+
+		exp := stmt.Expression
+		if call, ok := exp.(*ast.CallExpression); ok {
+			exp = call.Function
+		}
+
+		indexExp, ok := exp.(*ast.IndexExpression)
+		if !ok {
+			t.Errorf("%q: exp not *ast.IndexExpression. got=%T", tt.input, exp)
+			continue
+		}
+
+		if indexExp.Left.String() != tt.inner {
+			t.Errorf("%q: wrong indexed expression. expected=%q, got=%q", tt.input, tt.inner, indexExp.Left.String())
+		}
+	}
+}
+
+// TestParsingHashMethodCalls tests that calling an index expression calls the value it fetches, so hash["key"]() calls the function stored under "key"
+func TestParsingHashMethodCalls(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		function  string // String of the index expression being called
+		arguments int
+	}{
+		{`obj["greet"]()`, "(obj[greet])()", "(obj[greet])", 0},
+		{`obj["inner"]["add"](1, 2)`, "((obj[inner])[add])(1,2)", "((obj[inner])[add])", 2},
+		{`{"f": fn(x) { x }}["f"](5)`, "({f:fn(x)x}[f])(5)", "({f:fn(x)x}[f])", 1},
+		{`obj["greet"]() + 1`, "((obj[greet])() + 1)", "", 0},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: program.Statements does not contain 1 statement. got=%d", tt.input, len(program.Statements))
+		}
+
+		exp := program.Statements[0].(*ast.ExpressionStatement).Expression
+
+		if exp.String() != tt.expected {
+			t.Errorf("%q: wrong String. expected=%q, got=%q", tt.input, tt.expected, exp.String())
+		}
+
+		if tt.function == "" {
+			continue
+		}
+
+		call, ok := exp.(*ast.CallExpression)
+		if !ok {
+			t.Errorf("%q: exp not *ast.CallExpression. got=%T", tt.input, exp)
+			continue
+		}
+
+		if _, ok := call.Function.(*ast.IndexExpression); !ok || call.Function.String() != tt.function {
+			t.Errorf("%q: wrong function. expected index expression %q, got=%T %q", tt.input, tt.function, call.Function, call.Function.String())
+		}
+
+		if len(call.Arguments) != tt.arguments {
+			t.Errorf("%q: wrong number of arguments. expected=%d, got=%d", tt.input, tt.arguments, len(call.Arguments))
+		}
+	}
+}
+
+// TestParsingArrayComprehension tests parsing array comprehensions, with and without a filter
+func TestParsingArrayComprehension(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		condition string // "" when there's no filter
+	}{
+		{"[x * 2 for x in arr]", "[(x * 2) for x in arr]", ""},
+		{"[x for x in [1, 2, 3] if x > 1]", "[x for x in [1, 2, 3] if (x > 1)]", "(x > 1)"},
+		{"[f(n) for n in range(3)]", "[f(n) for n in range(3)]", ""},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+		comprehension, ok := stmt.Expression.(*ast.ArrayComprehension)
+		if !ok {
+			t.Fatalf("exp not *ast.ArrayComprehension. got=%T", stmt.Expression)
+		}
+
+		if comprehension.String() != tt.expected {
+			t.Errorf("comprehension.String() wrong. expected=%q, got=%q", tt.expected, comprehension.String())
+		}
+
+		if bound := boundString(comprehension.Condition); bound != tt.condition {
+			t.Errorf("comprehension.Condition wrong. expected=%q, got=%q", tt.condition, bound)
+		}
+	}
+
+	// An array whose first element isn't followed by for is still a literal
+	l := lexer.New("[x, y]")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if _, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.ArrayLiteral); !ok {
+		t.Errorf("[x, y] didn't parse as an array literal")
+	}
+
+	for _, input := range []string{"[x for 1 in arr]", "[x for x arr]"} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+// TestIllegalStringErrors tests that a string the lexer couldn't read is reported with its text
+func TestIllegalStringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"unterminated`, `1:1: Illegal token: "unterminated`},
+		{`let s = "bad \q";`, `1:9: Illegal token: "bad \q"`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser errors for %s. expected first=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+// TestParsingHashComprehension tests parsing hash comprehensions, with and without a filter
+func TestParsingHashComprehension(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"{x: x * x for x in arr}", "{x:(x * x) for x in arr}"},
+		{`{k: 1 for k in ["a", "b"] if k != "b"}`, "{k:1 for k in [a, b] if (k != b)}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+
+		comprehension, ok := stmt.Expression.(*ast.HashComprehension)
+		if !ok {
+			t.Fatalf("exp not *ast.HashComprehension. got=%T", stmt.Expression)
+		}
+
+		if comprehension.String() != tt.expected {
+			t.Errorf("comprehension.String() wrong. expected=%q, got=%q", tt.expected, comprehension.String())
+		}
+
+		testIdentifier(t, comprehension.Variable, comprehension.Variable.Value)
+	}
+
+	// for after a second pair isn't a comprehension
+	p := New(lexer.New(`{"a": 1, "b": 2 for x in arr}`))
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected parser errors for a for clause after the second pair")
+	}
+}
+
+// TestErrorPositions tests that parser errors report the line:col of the offending token
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x 5;", "1:7: Expected next token to be =, got INT instead"},
+		{"let a = 1;\n\tlet b = );", "2:10: Invalid prefix operator, type: )"},
+		{"let f = fn(x) {\n  x +\n}", "3:1: Invalid prefix operator, type: }"},
+		{"if (x {", "1:7: Expected next token to be ), got { instead"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong parser errors for %q. expected first=%q, got=%q", tt.input, tt.expected, errors)
+		}
+	}
+}
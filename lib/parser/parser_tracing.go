@@ -17,6 +17,9 @@ import (
 
 var traceLevel int = 0
 
+// Trace turns the parser trace output on or off
+var Trace = true
+
 // placeholder string for identLevel
 const traceIdentPlaceholder string = "\t"
 
@@ -27,6 +30,10 @@ func identLevel() string {
 
 // print parser strings, level #
 func tracePrint(fs string) {
+	if !Trace {
+		return
+	}
+
 	fmt.Printf("%s%s\n", identLevel(), fs)
 }
 
@@ -9,50 +9,229 @@ package repl
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"io"
+	"strings"
+	"time"
 
 	"github.com/tmoore2016/interpreter/lib/evaluator"
 	"github.com/tmoore2016/interpreter/lib/lexer"
 	"github.com/tmoore2016/interpreter/lib/object"
 	"github.com/tmoore2016/interpreter/lib/parser"
+	"github.com/tmoore2016/interpreter/lib/token"
 )
 
 // PROMPT = command prompt
 const PROMPT = ">> "
 
+// CONTINUATION_PROMPT is shown while reading the rest of an input with unclosed braces, parentheses or brackets
+const CONTINUATION_PROMPT = ".. "
+
 // Start REPL: Read, Evaluate, Print, Loop
 // Read from the input source until newline, pass the string to lexer, parse the lexer output, print the AST, evaluate the AST and print the eval.
+// A line that leaves a {, ( or [ open keeps reading lines until they're all closed, so a multi-line function can be pasted in and evaluated as one input.
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
-	env := object.NewEnvironment()
+	// readLine reads from the same reader as the REPL, so a script gets the lines after the one that called it
+	reader := bufio.NewReader(in)
+	env := newEnvironment(reader, out)
+
+	var options outputOptions
+
+	// Lines of an input that isn't balanced yet
+	var pending []string
 
 	for {
-		fmt.Printf(PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		if len(pending) == 0 {
+			fmt.Printf(PROMPT)
+		} else {
+			fmt.Printf(CONTINUATION_PROMPT)
+		}
+
+		line, ok := readInputLine(reader)
+		if !ok {
+			// Input ended partway through a block, evaluate it anyway so the parser reports what's missing
+			if len(pending) != 0 {
+				evalInput(out, strings.Join(pending, "\n"), env, options)
+			}
 			return
 		}
 
-		// Lex the input and write the parsed output line by line
-		line := scanner.Text()
-		l := lexer.New(line)
-		p := parser.New(l)
+		if len(pending) == 0 && options.toggle(line) {
+			continue
+		}
 
-		// If there are parser errors, print the errors
-		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+		// Lines starting with : are REPL commands rather than Doorkey code
+		if len(pending) == 0 && strings.HasPrefix(line, ":") {
+			if !runCommand(out, line, &env) {
+				return
+			}
 			continue
 		}
 
-		// Evaluate the input and write as output
-		evaluated := evaluator.Eval(program, env)
-		if evaluated != nil {
-			io.WriteString(out, evaluated.Inspect())
-			io.WriteString(out, "\n")
+		pending = append(pending, line)
+		input := strings.Join(pending, "\n")
+
+		if openDelimiters(input) > 0 {
+			continue
+		}
+
+		pending = nil
+		evalInput(out, input, env, options)
+	}
+}
+
+// readInputLine reads the next line without its line ending, returning false once the input has ended
+func readInputLine(reader *bufio.Reader) (string, bool) {
+	line, err := reader.ReadString('\n')
+
+	if err != nil && line == "" {
+		return "", false
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+
+	return strings.TrimSuffix(line, "\r"), true
+}
+
+// outputOptions are the REPL's output modes, each toggled by a . line
+type outputOptions struct {
+	json   bool // .json, one JSON object per result for tools driving the REPL instead of Inspect output
+	tokens bool // .tokens, print each input's tokens before its result
+	time   bool // .time, print how long each evaluation took after its result
+}
+
+// toggle flips the output mode a . line names, and returns false if the line isn't one
+func (o *outputOptions) toggle(line string) bool {
+	switch line {
+	case ".json":
+		o.json = !o.json
+	case ".tokens":
+		o.tokens = !o.tokens
+	case ".time":
+		o.time = !o.time
+	default:
+		return false
+	}
+
+	return true
+}
+
+// commandHelp lists the REPL commands for :help
+const commandHelp = `:env    list the current bindings
+:reset  start over with an empty environment
+:help   show this list
+:quit   exit the REPL
+.json   toggle JSON output
+.tokens toggle printing each input's tokens
+.time   toggle printing how long each evaluation takes
+`
+
+// newEnvironment creates an empty REPL environment that reads and writes through the REPL's input and output. Each input is its own program, so a return at the top level can't end anything and is an error.
+func newEnvironment(in io.Reader, out io.Writer) *object.Environment {
+	env := object.NewEnvironment()
+	env.SetIO(in, out)
+	env.SetTopLevelReturn(false)
+
+	return env
+}
+
+// runCommand runs a : command, returning false when the REPL should exit
+func runCommand(out io.Writer, line string, env **object.Environment) bool {
+	switch strings.TrimSpace(line) {
+	case ":quit":
+		return false
+
+	case ":env":
+		for _, name := range (*env).Names() {
+			val, _ := (*env).Get(name)
+			io.WriteString(out, name+" = "+val.Inspect()+"\n")
+		}
+
+	case ":reset":
+		*env = newEnvironment((*env).Input(), (*env).Output())
+
+	case ":help":
+		io.WriteString(out, commandHelp)
+
+	default:
+		io.WriteString(out, "unknown command "+line+", try :help\n")
+	}
+
+	return true
+}
+
+// evalInput lexes, parses and evaluates one input, writing its result or errors
+func evalInput(out io.Writer, input string, env *object.Environment, options outputOptions) {
+	jsonMode := options.json
+
+	if options.tokens {
+		printTokens(out, lexer.New(input).Tokens(), jsonMode)
+	}
+
+	l := lexer.New(input)
+	p := parser.New(l)
+
+	// If there are parser errors, print the errors
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		if jsonMode {
+			writeJSON(out, map[string]interface{}{"type": "parser_error", "messages": p.Errors()})
+		} else {
+			printParserErrors(out, p.Errors())
+		}
+		return
+	}
+
+	// Evaluate the input and write as output
+	start := time.Now()
+	evaluated := evaluator.Eval(program, env)
+	elapsed := time.Since(start)
+
+	if evaluated != nil && jsonMode {
+		printJSONResult(out, evaluated)
+	} else if evaluated != nil {
+		io.WriteString(out, evaluated.Inspect())
+		io.WriteString(out, "\n")
+	}
+
+	if options.time {
+		printElapsed(out, elapsed, jsonMode)
+	}
+}
+
+// printElapsed writes how long an evaluation took, as (2.3ms) or a {"type": "time"} JSON object
+func printElapsed(out io.Writer, elapsed time.Duration, jsonMode bool) {
+	// Keep about two significant digits, 2.3ms rather than 2.345678ms
+	for _, unit := range []time.Duration{time.Second, time.Millisecond, time.Microsecond} {
+		if elapsed >= unit {
+			elapsed = elapsed.Round(unit / 10)
+			break
+		}
+	}
+
+	if jsonMode {
+		writeJSON(out, map[string]interface{}{"type": "time", "elapsed": elapsed.String()})
+		return
+	}
+
+	io.WriteString(out, "("+elapsed.String()+")\n")
+}
+
+// openDelimiters returns how many more {, ( and [ the input opens than it closes. It lexes the input so delimiters inside strings and comments don't count.
+func openDelimiters(input string) int {
+	depth := 0
+
+	for _, tok := range lexer.New(input).Tokens() {
+		switch tok.Type {
+		case token.LBRACE, token.LPAREN, token.LBRACKET:
+			depth++
+		case token.RBRACE, token.RPAREN, token.RBRACKET:
+			depth--
 		}
 	}
+
+	return depth
 }
 
 // printParserErrors writes any parser errors found
@@ -63,3 +242,52 @@ func printParserErrors(out io.Writer, errors []string) {
 		io.WriteString(out, "\t"+msg+"\n")
 	}
 }
+
+// printTokens writes the tokens of an input on one line as TYPE "literal" pairs, or as a {"type": "tokens"} JSON object
+func printTokens(out io.Writer, tokens []token.Token, jsonMode bool) {
+	if jsonMode {
+		list := make([]map[string]string, len(tokens))
+		for i, tok := range tokens {
+			list[i] = map[string]string{"type": string(tok.Type), "literal": tok.Literal}
+		}
+
+		writeJSON(out, map[string]interface{}{"type": "tokens", "tokens": list})
+		return
+	}
+
+	parts := make([]string, len(tokens))
+	for i, tok := range tokens {
+		parts[i] = fmt.Sprintf("%s %q", tok.Type, tok.Literal)
+	}
+
+	io.WriteString(out, "tokens: "+strings.Join(parts, ", ")+"\n")
+}
+
+// printJSONResult writes an evaluated result as a JSON object: {"type": "result", "value": ...} or {"type": "error", "message": ...}
+func printJSONResult(out io.Writer, evaluated object.Object) {
+	if err, ok := evaluated.(*object.Error); ok {
+		result := map[string]interface{}{"type": "error", "message": err.Message}
+		if len(err.Trace) > 0 {
+			result["trace"] = err.Trace
+		}
+
+		writeJSON(out, result)
+		return
+	}
+
+	encoded, err := object.ToJSON(evaluated)
+	if err != nil {
+		writeJSON(out, map[string]interface{}{"type": "error", "message": err.Error()})
+		return
+	}
+
+	writeJSON(out, map[string]interface{}{"type": "result", "value": json.RawMessage(encoded)})
+}
+
+// writeJSON writes a value as a single line of JSON
+func writeJSON(out io.Writer, value interface{}) {
+	encoded, _ := json.Marshal(value)
+
+	io.WriteString(out, string(encoded))
+	io.WriteString(out, "\n")
+}
@@ -0,0 +1,231 @@
+/*
+REPL tests for
+Doorkey, a Monkey Derivative
+by Travis Moore
+By following "Writing an Interpreter in Go" by Thorsten Ball, https://interpreterbook.com/
+*/
+
+package repl
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmoore2016/interpreter/lib/evaluator"
+)
+
+// testStart runs the REPL over the input lines and returns everything it wrote
+func testStart(input string) string {
+	var out bytes.Buffer
+
+	Start(strings.NewReader(input), &out)
+
+	return out.String()
+}
+
+// TestStart tests that each line is evaluated in a shared environment and its result written
+func TestStart(t *testing.T) {
+	output := testStart("let x = 5;\nx * 2\n")
+
+	if output != "10\n" {
+		t.Errorf("wrong REPL output. expected=%q, got=%q", "10\n", output)
+	}
+}
+
+// TestReadLineInput tests that readLine and puts use the REPL's own input and output, so readLine gets the line after the one that called it, even after :reset
+func TestReadLineInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let name = readLine();\nDoorkey\nputs(\"hi \" + name)\n", "hi Doorkey\nnull\n"},
+		{"readLine()\r\nfirst\r\n", "first\n"},
+		{":reset\nreadLine()\nsecond\n", "second\n"},
+		{"readLine()\n", "null\n"},
+	}
+
+	for _, tt := range tests {
+		if output := testStart(tt.input); output != tt.expected {
+			t.Errorf("wrong REPL output for %q. expected=%q, got=%q", tt.input, tt.expected, output)
+		}
+	}
+}
+
+// TestTopLevelReturn tests that a return outside of a function is an error in the REPL
+func TestTopLevelReturn(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"return 5;\n", "ERROR: return outside of a function\n"},
+		{"if (true) { return 1; }\n", "ERROR: return outside of a function\n"},
+		{"let f = fn() { return 5; 6 }; f()\n", "5\n"},
+		{":reset\nreturn 5;\n", "ERROR: return outside of a function\n"},
+	}
+
+	for _, tt := range tests {
+		if output := testStart(tt.input); output != tt.expected {
+			t.Errorf("wrong REPL output for %q. expected=%q, got=%q", tt.input, tt.expected, output)
+		}
+	}
+
+	// The setting is on the REPL's environment, so the evaluator's default for everything else is untouched
+	if !evaluator.TopLevelReturn {
+		t.Errorf("the REPL turned TopLevelReturn off")
+	}
+}
+
+// TestJSONMode tests that .json switches results and errors to one JSON object per line, and back again
+func TestJSONMode(t *testing.T) {
+	output := testStart(".json\n[1, \"two\"]\nlet f = fn() { 1 + true }; f()\nlet = 5\n.json\n2\n")
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+
+	if len(lines) != 4 {
+		t.Fatalf("wrong number of output lines. expected=4, got=%d (%q)", len(lines), output)
+	}
+
+	for _, line := range lines[:3] {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("output isn't valid JSON. got=%q", line)
+		}
+	}
+
+	expected := []string{
+		`{"type":"result","value":[1,"two"]}`,
+		`{"message":"type mismatch: INTEGER + BOOLEAN","trace":["f"],"type":"error"}`,
+	}
+
+	for i, want := range expected {
+		if lines[i] != want {
+			t.Errorf("wrong output on line %d. expected=%q, got=%q", i, want, lines[i])
+		}
+	}
+
+	var parserError map[string]interface{}
+	json.Unmarshal([]byte(lines[2]), &parserError)
+
+	if parserError["type"] != "parser_error" {
+		t.Errorf("parser errors weren't a parser_error object. got=%q", lines[2])
+	}
+
+	if lines[3] != "2" {
+		t.Errorf("a second .json didn't switch back to Inspect output. got=%q", lines[3])
+	}
+}
+
+// TestMultiLineInput tests that lines are read until braces, parentheses and brackets balance, then evaluated as one input
+func TestMultiLineInput(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let newAdder = fn(x) {\n  fn(y) { x + y };\n};\nlet addTwo = newAdder(2);\naddTwo(3)\n", "5\n"},
+		{"if (1 > 2) {\n  10\n} else {\n  20\n}\n", "20\n"},
+		{"[1,\n2,\n3]\n", "[1, 2, 3]\n"},
+		{"len(\"{\")\n", "1\n"},
+		{"let f = fn() {\n  1 // }\n}\nf()\n", "1\n"},
+		{"1 + (2\n", "Uh oh, parser error(s) detected:\n\t1:7: Expected next token to be ), got EOF instead\n"},
+		{"}\n5\n", "Uh oh, parser error(s) detected:\n\t1:1: Invalid prefix operator, type: }\n5\n"},
+	}
+
+	for _, tt := range tests {
+		if output := testStart(tt.input); output != tt.expected {
+			t.Errorf("wrong REPL output for %q. expected=%q, got=%q", tt.input, tt.expected, output)
+		}
+	}
+}
+
+// TestCommands tests the : REPL commands
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let b = 2;\nlet a = 1;\n:env\n", "a = 1\nb = 2\n"},
+		{"let a = 1;\n:reset\n:env\na\n", "ERROR: Identifier not found: a\n"},
+		{"1\n:quit\n2\n", "1\n"},
+		{":help\n", commandHelp},
+		{":nope\n", "unknown command :nope, try :help\n"},
+		{"let f = fn(x) {\n:quit\n}\n1\n", "Uh oh, parser error(s) detected:\n\t2:1: Invalid prefix operator, type: :\n1\n"},
+	}
+
+	for _, tt := range tests {
+		if output := testStart(tt.input); output != tt.expected {
+			t.Errorf("wrong REPL output for %q. expected=%q, got=%q", tt.input, tt.expected, output)
+		}
+	}
+}
+
+// TestTokensMode tests that .tokens prints each input's tokens before its result, until it's toggled off
+func TestTokensMode(t *testing.T) {
+	output := testStart(".tokens\nlet x = 5;\nx + 1\n.tokens\nx\n")
+
+	expected := `tokens: LET "let", IDENT "x", = "=", INT "5", ; ";"
+tokens: IDENT "x", + "+", INT "1"
+6
+5
+`
+
+	if output != expected {
+		t.Errorf("wrong REPL output. expected=%q, got=%q", expected, output)
+	}
+
+	output = testStart(".json\n.tokens\n1\n")
+	expected = `{"tokens":[{"literal":"1","type":"INT"}],"type":"tokens"}` + "\n" + `{"type":"result","value":1}` + "\n"
+
+	if output != expected {
+		t.Errorf("wrong REPL output in JSON mode. expected=%q, got=%q", expected, output)
+	}
+}
+
+// TestTimeMode tests that .time prints how long each evaluation took after its result, until it's toggled off
+func TestTimeMode(t *testing.T) {
+	output := testStart(".time\nlet x = 5;\nx + 1\n.time\nx\n")
+
+	pattern := regexp.MustCompile(`^\(([0-9.]+(ns|µs|ms|s))\)\n6\n\(([0-9.]+(ns|µs|ms|s))\)\n5\n$`)
+	if !pattern.MatchString(output) {
+		t.Errorf("wrong REPL output. expected a timing line after each result, got=%q", output)
+	}
+
+	output = testStart(".json\n.time\n1\n")
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+
+	if len(lines) != 2 || lines[0] != `{"type":"result","value":1}` {
+		t.Fatalf("wrong REPL output in JSON mode. got=%q", output)
+	}
+
+	var timing map[string]string
+	if err := json.Unmarshal([]byte(lines[1]), &timing); err != nil {
+		t.Fatalf("timing line isn't JSON: %v", err)
+	}
+
+	if _, err := time.ParseDuration(timing["elapsed"]); timing["type"] != "time" || err != nil {
+		t.Errorf("wrong timing line. got=%q", lines[1])
+	}
+}
+
+// TestPrintElapsed tests that durations are rounded to about two significant digits
+func TestPrintElapsed(t *testing.T) {
+	tests := []struct {
+		elapsed  time.Duration
+		expected string
+	}{
+		{2345678 * time.Nanosecond, "(2.3ms)\n"},
+		{1500 * time.Millisecond, "(1.5s)\n"},
+		{12345 * time.Nanosecond, "(12.3µs)\n"},
+		{450 * time.Nanosecond, "(450ns)\n"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		printElapsed(&out, tt.elapsed, false)
+
+		if out.String() != tt.expected {
+			t.Errorf("printElapsed(%d) wrong. expected=%q, got=%q", tt.elapsed, tt.expected, out.String())
+		}
+	}
+}
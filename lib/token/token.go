@@ -16,16 +16,22 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int // line the token starts on, from 1
+	Column  int // column the token starts at, from 1
+	Start   int // byte offset of the token's first char in the input
+	End     int // byte offset just past the token's last char, so input[Start:End] is its source text
 }
 
 // Constants
 const (
 	ILLEGAL = "ILLEGAL" // Invalid or unknown Token/Character
 	EOF     = "EOF"     // End of file
+	COMMENT = "COMMENT" // A // comment, only produced by a lexer that keeps comments
 
 	// Identifiers and literals
 	IDENT  = "IDENT"  // Name
 	INT    = "INT"    // Integers
+	FLOAT  = "FLOAT"  // Floating-point numbers, 3.14
 	STRING = "STRING" // String type
 
 	// Operators
@@ -35,10 +41,16 @@ const (
 	NOT      = "!"
 	MULTIPLY = "*"
 	DIVIDE   = "/"
+	MODULO   = "%"
 	LT       = "<"
 	GT       = ">"
+	LTE      = "<="
+	GTE      = ">="
 	EQ       = "=="
 	NOT_EQ   = "!="
+	COALESCE = "??"
+	QUESTION = "?" // cond ? a : b
+	PIPE     = "|>"
 
 	// Delimiters
 	COMMA     = ","
@@ -55,22 +67,34 @@ const (
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	IF       = "IF"
 	ELSE     = "ELSE"
+	WHILE    = "WHILE"
+	FOR      = "FOR" // 'for', in a comprehension
 	RETURN   = "RETURN"
+	NOT_WORD = "NOT_WORD" // 'not', an alias of the ! prefix
+	NULL     = "NULL"
+	IN       = "IN" // 'in', the body of a let-in expression or the collection of a comprehension
 )
 
 // input for keywords
 var keywords = map[string]TokenType{
 	"fn":     FUNCTION,
 	"let":    LET,
+	"const":  CONST,
 	"true":   TRUE,
 	"false":  FALSE,
 	"if":     IF,
 	"else":   ELSE,
+	"while":  WHILE,
+	"for":    FOR,
 	"return": RETURN,
+	"not":    NOT_WORD,
+	"null":   NULL,
+	"in":     IN,
 }
 
 // LookupIdent determines whether identifier is a keyword
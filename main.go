@@ -7,14 +7,45 @@ By following "Writing an Interpreter in Go" by Thorsten Ball, https://interprete
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
 
+	"github.com/tmoore2016/interpreter/lib/evaluator"
+	"github.com/tmoore2016/interpreter/lib/lexer"
+	"github.com/tmoore2016/interpreter/lib/object"
+	"github.com/tmoore2016/interpreter/lib/parser"
 	"github.com/tmoore2016/interpreter/lib/repl"
 )
 
 func main() {
+	check := flag.Bool("check", false, "parse a file (or stdin) and report syntax errors without evaluating")
+	eval := flag.String("e", "", "evaluate the given source and print the result")
+	keepGoing := flag.Bool("continue", false, "keep evaluating after a top-level runtime error and report every error")
+	flag.Parse()
+
+	evaluator.ContinueOnError = *keepGoing
+
+	// Syntax check mode, for CI and editors: doorkey -check file.dk
+	if *check {
+		parser.Trace = false
+		os.Exit(runCheck(flag.Arg(0), os.Stdin, os.Stdout))
+	}
+
+	// Evaluate a string: doorkey -e "2 + 2"
+	if *eval != "" {
+		parser.Trace = false
+		os.Exit(runSource(*eval, os.Stdout))
+	}
+
+	// File mode: doorkey file.dk
+	if flag.NArg() > 0 {
+		parser.Trace = false
+		os.Exit(runFile(flag.Arg(0), os.Stdout))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err) // End program with stack trace
@@ -24,3 +55,96 @@ func main() {
 	fmt.Printf(" Welcome to Doorkey a Monkey derivative!\n I can evaluate your input, go ahead and give me a try.\n")
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+// runCheck parses the file at path, or stdin if path is empty, and writes any parser errors to out. It returns the process exit code: 0 if the source is clean, 1 if there are errors.
+func runCheck(path string, stdin io.Reader, out io.Writer) int {
+	name := path
+	src, err := readSource(path, stdin)
+
+	if name == "" {
+		name = "stdin"
+	}
+
+	if err != nil {
+		fmt.Fprintf(out, "%s: %s\n", name, err)
+		return 1
+	}
+
+	errors := checkSyntax(src)
+
+	for _, msg := range errors {
+		fmt.Fprintf(out, "%s: %s\n", name, msg)
+	}
+
+	if len(errors) != 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// runFile evaluates the file at path with runSource
+func runFile(path string, out io.Writer) int {
+	src, err := os.ReadFile(path)
+
+	if err != nil {
+		fmt.Fprintf(out, "%s: %s\n", path, err)
+		return 1
+	}
+
+	return runSource(string(src), out)
+}
+
+// runSource parses and evaluates a whole program. Like the REPL, the value of the last top-level expression is written to out unless it is null. It returns the process exit code: 0 on success, 1 on a parser or runtime error.
+func runSource(src string, out io.Writer) int {
+	l := lexer.New(src)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintln(out, msg)
+		}
+
+		return 1
+	}
+
+	env := object.NewEnvironment()
+	env.SetIO(os.Stdin, out)
+	evaluated := evaluator.Eval(program, env)
+
+	if evaluated == nil || evaluated.Type() == object.NULL_OBJ {
+		return 0
+	}
+
+	fmt.Fprintln(out, evaluated.Inspect())
+
+	if evaluated.Type() == object.ERROR_OBJ {
+		return 1
+	}
+
+	return 0
+}
+
+// readSource reads the file at path, or all of stdin if path is empty
+func readSource(path string, stdin io.Reader) (string, error) {
+	var src []byte
+	var err error
+
+	if path == "" {
+		src, err = io.ReadAll(stdin)
+	} else {
+		src, err = os.ReadFile(path)
+	}
+
+	return string(src), err
+}
+
+// checkSyntax parses the source without evaluating it and returns any parser errors
+func checkSyntax(src string) []string {
+	l := lexer.New(src)
+	p := parser.New(l)
+	p.ParseProgram()
+
+	return p.Errors()
+}
@@ -0,0 +1,173 @@
+/*
+Main tests for
+Doorkey, a Monkey Derivative
+by Travis Moore
+By following "Writing an Interpreter in Go" by Thorsten Ball, https://interpreterbook.com/
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tmoore2016/interpreter/lib/evaluator"
+)
+
+// writeTestFile writes a Doorkey source file into a temporary directory and returns its path
+func writeTestFile(t *testing.T, src string) string {
+	path := filepath.Join(t.TempDir(), "test.dk")
+
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("could not write test file: %s", err)
+	}
+
+	return path
+}
+
+// TestCheckValidFile tests that a clean file exits 0 with no output
+func TestCheckValidFile(t *testing.T) {
+	path := writeTestFile(t, "let add = fn(x, y) { x + y; };\nadd(1, 2);\n")
+
+	var out bytes.Buffer
+	code := runCheck(path, strings.NewReader(""), &out)
+
+	if code != 0 {
+		t.Errorf("wrong exit code. expected=0, got=%d", code)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("expected no output, got=%q", out.String())
+	}
+}
+
+// TestCheckInvalidFile tests that a file with syntax errors reports them and exits 1
+func TestCheckInvalidFile(t *testing.T) {
+	path := writeTestFile(t, "let = 5;\nlet x 10;\n")
+
+	var out bytes.Buffer
+	code := runCheck(path, strings.NewReader(""), &out)
+
+	if code != 1 {
+		t.Errorf("wrong exit code. expected=1, got=%d", code)
+	}
+
+	if !strings.Contains(out.String(), "Expected next token to be IDENT, got = instead") {
+		t.Errorf("output doesn't report the parser error. got=%q", out.String())
+	}
+
+	if !strings.HasPrefix(out.String(), path+": ") {
+		t.Errorf("output doesn't name the file. got=%q", out.String())
+	}
+}
+
+// TestCheckStdin tests that source is read from stdin when no file is given
+func TestCheckStdin(t *testing.T) {
+	var out bytes.Buffer
+
+	if code := runCheck("", strings.NewReader("1 + 2;"), &out); code != 0 {
+		t.Errorf("wrong exit code for valid stdin. expected=0, got=%d (%q)", code, out.String())
+	}
+
+	out.Reset()
+
+	if code := runCheck("", strings.NewReader("5 = 3;"), &out); code != 1 {
+		t.Errorf("wrong exit code for invalid stdin. expected=1, got=%d", code)
+	}
+
+	if out.String() != "stdin: invalid assignment target\n" {
+		t.Errorf("wrong output. got=%q", out.String())
+	}
+}
+
+// TestCheckMissingFile tests that an unreadable file exits 1
+func TestCheckMissingFile(t *testing.T) {
+	var out bytes.Buffer
+
+	if code := runCheck(filepath.Join(t.TempDir(), "missing.dk"), strings.NewReader(""), &out); code != 1 {
+		t.Errorf("wrong exit code. expected=1, got=%d", code)
+	}
+}
+
+// TestRunFilePrintsLastExpression tests that file mode prints the value of the final top-level expression
+func TestRunFilePrintsLastExpression(t *testing.T) {
+	path := writeTestFile(t, "let a = 2;\nlet b = 2;\na + b\n")
+
+	var out bytes.Buffer
+	code := runFile(path, &out)
+
+	if code != 0 {
+		t.Errorf("wrong exit code. expected=0, got=%d", code)
+	}
+
+	if out.String() != "4\n" {
+		t.Errorf("wrong output. expected=%q, got=%q", "4\n", out.String())
+	}
+}
+
+// TestRunSource tests the output and exit code of evaluating whole programs, as with the -e flag
+func TestRunSource(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedOut  string
+		expectedCode int
+	}{
+		{"2 + 2", "4\n", 0},
+		// A top-level return ends the program with its value
+		{"return 42; 5", "42\n", 0},
+		{"let x = 1; if (x > 0) { return 7; } 8", "7\n", 0},
+		{"let x = 5;", "", 0},
+		{"if (false) { 1 }", "", 0},
+		{`"a" + "b"`, "ab\n", 0},
+		{"1 + true", "ERROR: type mismatch: INTEGER + BOOLEAN\n", 1},
+		{"let x 1;", "1:7: Expected next token to be =, got INT instead\n", 1},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		code := runSource(tt.input, &out)
+
+		if code != tt.expectedCode {
+			t.Errorf("%q: wrong exit code. expected=%d, got=%d", tt.input, tt.expectedCode, code)
+		}
+
+		if out.String() != tt.expectedOut {
+			t.Errorf("%q: wrong output. expected=%q, got=%q", tt.input, tt.expectedOut, out.String())
+		}
+	}
+}
+
+// TestRunSourceContinueOnError tests that with -continue every top-level runtime error is written and the exit code is still 1
+func TestRunSourceContinueOnError(t *testing.T) {
+	defer func() { evaluator.ContinueOnError = false }()
+	evaluator.ContinueOnError = true
+
+	var out bytes.Buffer
+	code := runSource("let a = 1 + true;\nlet b = 2;\nputs(c);\nb", &out)
+
+	expected := "ERROR: type mismatch: INTEGER + BOOLEAN\nERROR: Identifier not found: c\n"
+
+	if code != 1 {
+		t.Errorf("wrong exit code. expected=1, got=%d", code)
+	}
+
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+}
+
+// TestRunExampleFile tests the bundled calculator example
+func TestRunExampleFile(t *testing.T) {
+	var out bytes.Buffer
+
+	if code := runFile(filepath.Join("examples", "calc.dk"), &out); code != 0 {
+		t.Fatalf("wrong exit code. expected=0, got=%d (%q)", code, out.String())
+	}
+
+	if out.String() != "4\n" {
+		t.Errorf("wrong output. expected=%q, got=%q", "4\n", out.String())
+	}
+}